@@ -18,14 +18,18 @@ package v1alpha1
 
 import (
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 type CronJobTemplateSpec struct {
-	// Standard object's metadata of the jobs created from this template.
+	// Standard object's metadata of the cronjobs created from this template.
+	// Only labels and annotations are applied to the cronjobs.
 	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
 	// +optional
 	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
@@ -36,13 +40,472 @@ type CronJobTemplateSpec struct {
 	Spec batchv1.CronJobSpec `json:"spec,omitempty" protobuf:"bytes,2,opt,name=spec"`
 }
 
+// DefaultTolerationsPolicy describes which tolerations are added to the pods of the generated CronJobs.
+// +kubebuilder:validation:Enum=None;DaemonSet
+type DefaultTolerationsPolicy string
+
+const (
+	// DefaultTolerationsNone adds no tolerations besides the ones of the pod template.
+	DefaultTolerationsNone DefaultTolerationsPolicy = "None"
+
+	// DefaultTolerationsDaemonSet adds the node.kubernetes.io/* tolerations that the DaemonSet
+	// controller adds to its pods, so that jobs keep running on not-ready, unreachable,
+	// unschedulable and resource pressured nodes.
+	DefaultTolerationsDaemonSet DefaultTolerationsPolicy = "DaemonSet"
+)
+
+// NodeHealthPolicyType describes how the CronJob of a cordoned or NotReady node is handled.
+// +kubebuilder:validation:Enum=Keep;Suspend;Remove
+type NodeHealthPolicyType string
+
+const (
+	// NodeHealthPolicyKeep treats cordoned and NotReady nodes like healthy ones.
+	NodeHealthPolicyKeep NodeHealthPolicyType = "Keep"
+
+	// NodeHealthPolicySuspend suspends the CronJob of the node while it is cordoned or NotReady,
+	// and resumes it once the node recovers.
+	NodeHealthPolicySuspend NodeHealthPolicyType = "Suspend"
+
+	// NodeHealthPolicyRemove deletes the CronJob of the node while it is cordoned or NotReady.
+	NodeHealthPolicyRemove NodeHealthPolicyType = "Remove"
+)
+
+// NodeHealthPolicy describes how the CronJobs of unhealthy nodes are handled.
+type NodeHealthPolicy struct {
+	// Type of the policy. Can be "Keep", "Suspend" or "Remove". Defaults to Keep.
+	// +optional
+	// +kubebuilder:default=Keep
+	Type NodeHealthPolicyType `json:"type,omitempty" protobuf:"bytes,1,opt,name=type,casttype=NodeHealthPolicyType"`
+
+	// NotReadyGracePeriod is how long a node has to be NotReady before the policy applies to it.
+	// Cordoned nodes are handled immediately. Defaults to 5m.
+	// +optional
+	NotReadyGracePeriod *metav1.Duration `json:"notReadyGracePeriod,omitempty" protobuf:"bytes,2,opt,name=notReadyGracePeriod"`
+}
+
+// LabelPropagationPolicy describes whether the labels of a CronSet are copied to its CronJobs.
+// +kubebuilder:validation:Enum=All;None
+type LabelPropagationPolicy string
+
+const (
+	// LabelPropagationAll copies every label of the CronSet to its CronJobs.
+	LabelPropagationAll LabelPropagationPolicy = "All"
+
+	// LabelPropagationNone doesn't copy the labels of the CronSet to its CronJobs.
+	LabelPropagationNone LabelPropagationPolicy = "None"
+)
+
+// CronSetUpdateStrategyType is the strategy used to roll a new CronJob template out to the nodes.
+// +kubebuilder:validation:Enum=RollingUpdate;OnDelete
+type CronSetUpdateStrategyType string
+
+const (
+	// RollingUpdateCronSetStrategyType updates the CronJobs in batches, waiting for the next run of
+	// every updated CronJob to succeed before updating more.
+	RollingUpdateCronSetStrategyType CronSetUpdateStrategyType = "RollingUpdate"
+
+	// OnDeleteCronSetStrategyType only applies the new template to the CronJobs that are deleted.
+	OnDeleteCronSetStrategyType CronSetUpdateStrategyType = "OnDelete"
+)
+
+// CronSetUpdateStrategy describes how a change of the CronJob template is rolled out.
+type CronSetUpdateStrategy struct {
+	// Type of the update strategy. Can be "RollingUpdate" or "OnDelete". Defaults to RollingUpdate.
+	// +optional
+	// +kubebuilder:default=RollingUpdate
+	Type CronSetUpdateStrategyType `json:"type,omitempty" protobuf:"bytes,1,opt,name=type,casttype=CronSetUpdateStrategyType"`
+
+	// RollingUpdate configures the rolling update. Only used when type is RollingUpdate.
+	// +optional
+	RollingUpdate *RollingUpdateCronSet `json:"rollingUpdate,omitempty" protobuf:"bytes,2,opt,name=rollingUpdate"`
+
+	// Canary applies a template change to a subset of the nodes first, and only rolls it out to
+	// the other nodes once the canary CronJobs succeeded. Only used when type is RollingUpdate.
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty" protobuf:"bytes,3,opt,name=canary"`
+}
+
+// CanaryStrategy describes the canary nodes of a template change and when it is promoted.
+type CanaryStrategy struct {
+	// Nodes is the number or percentage of the eligible nodes, in the order of their names, that
+	// get the new template first. Ignored when selector is set. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:XIntOrString
+	Nodes *intstr.IntOrString `json:"nodes,omitempty" protobuf:"bytes,1,opt,name=nodes"`
+
+	// Selector is a label query over the eligible nodes that get the new template first.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty" protobuf:"bytes,2,opt,name=selector"`
+
+	// SuccessfulRuns is the number of jobs every canary CronJob has to complete successfully
+	// before the template is promoted to the other nodes. It should not exceed the
+	// successfulJobsHistoryLimit of the template, as older jobs are not visible. Defaults to 1.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	SuccessfulRuns int32 `json:"successfulRuns,omitempty" protobuf:"varint,3,opt,name=successfulRuns"`
+
+	// Timeout is how long the canary may progress before it fails and every node is rolled back,
+	// e.g. when no canary node is eligible or the canary CronJobs don't run. It should exceed the
+	// time the canary CronJobs need for successfulRuns runs. Defaults to 24h.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty" protobuf:"bytes,4,opt,name=timeout"`
+}
+
+// RollingUpdateCronSet configures a rolling update of the CronJobs.
+type RollingUpdateCronSet struct {
+	// MaxUnavailable is the maximum number of updated CronJobs whose next run hasn't succeeded yet,
+	// i.e. the size of a batch. It can be an absolute number or a percentage of the eligible nodes,
+	// rounded up. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:XIntOrString
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty" protobuf:"bytes,1,opt,name=maxUnavailable"`
+
+	// Partition is the number of eligible nodes, in the order of their names, whose CronJobs are
+	// kept on the previous template. Defaults to 0, which updates every node.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Partition *int32 `json:"partition,omitempty" protobuf:"varint,2,opt,name=partition"`
+}
+
+// CronSetRollback requests a rollback of the CronJob template to a previous revision.
+type CronSetRollback struct {
+	// RevisionName is the name of the ControllerRevision of the CronSet to roll back to.
+	RevisionName string `json:"revisionName" protobuf:"bytes,1,opt,name=revisionName"`
+}
+
+// Stagger spreads the schedules of the CronJobs across the nodes.
+type Stagger struct {
+	// Window is the period over which the schedules are spread, up to 24h. Every node gets a stable
+	// offset within the window, derived from a hash of its node identifier, which is added to the
+	// schedule. Windows longer than 1h also shift the hour of schedules with a fixed time of day.
+	Window metav1.Duration `json:"window" protobuf:"bytes,1,opt,name=window"`
+}
+
+// TerminationProtection delays the termination of nodes while Jobs of the CronSet run on them.
+type TerminationProtection struct {
+	// MaxDelay bounds how long the termination of a node is delayed once the node is cordoned
+	// or deleted. Afterwards, the node is released even though the Job is still running.
+	// Defaults to 1h.
+	// +optional
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty" protobuf:"bytes,1,opt,name=maxDelay"`
+}
+
+// CronJobAdoption selects pre-existing CronJobs the CronSet takes over.
+type CronJobAdoption struct {
+	// Selector is a label query over the CronJobs in the namespace of the CronSet. It must not be
+	// empty. A matching CronJob without controller, whose pods are pinned to an eligible node with
+	// nodeName or a kubernetes.io/hostname nodeSelector, becomes the CronJob of the node.
+	Selector metav1.LabelSelector `json:"selector" protobuf:"bytes,1,opt,name=selector"`
+}
+
+// DeletionPolicy decides what happens to the CronJobs when their CronSet is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the CronJobs along with the CronSet.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan keeps the CronJobs without their owner reference and owner labels.
+	// A CronSet with the same name created later in the same namespace adopts them back.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// NodeRemovalHook is a Job run on a node leaving the CronSet, before the CronJob of the node is deleted.
+type NodeRemovalHook struct {
+	// JobTemplate is the template of the Job. Its pods are pinned to the departing node, and the
+	// node attributes are rendered into it like into the CronJob template.
+	JobTemplate batchv1.JobTemplateSpec `json:"jobTemplate" protobuf:"bytes,1,opt,name=jobTemplate"`
+
+	// Timeout bounds how long the Job may run. It is set as the activeDeadlineSeconds of the Job,
+	// after which the Job fails and the CronJob is deleted anyway. Defaults to 10m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty" protobuf:"bytes,2,opt,name=timeout"`
+}
+
+// ScaleDownProtectionPolicy decides how the cluster autoscalers are kept from removing the nodes
+// running a Job of the CronSet.
+// +kubebuilder:validation:Enum=None;Pod;Node
+type ScaleDownProtectionPolicy string
+
+const (
+	// ScaleDownProtectionNone doesn't protect the nodes.
+	ScaleDownProtectionNone ScaleDownProtectionPolicy = "None"
+
+	// ScaleDownProtectionPod annotates the pods of the Jobs with
+	// cluster-autoscaler.kubernetes.io/safe-to-evict: "false" and karpenter.sh/do-not-disrupt: "true".
+	ScaleDownProtectionPod ScaleDownProtectionPolicy = "Pod"
+
+	// ScaleDownProtectionNode annotates the node with
+	// cluster-autoscaler.kubernetes.io/scale-down-disabled: "true" and karpenter.sh/do-not-disrupt: "true"
+	// while a Job of the CronSet is running on it.
+	ScaleDownProtectionNode ScaleDownProtectionPolicy = "Node"
+)
+
+// OverridePatchType is the type of the patch of a template override.
+// +kubebuilder:validation:Enum=StrategicMerge;JSON
+type OverridePatchType string
+
+const (
+	// OverridePatchStrategicMerge is a strategic merge patch, e.g. containers are merged by name.
+	OverridePatchStrategicMerge OverridePatchType = "StrategicMerge"
+
+	// OverridePatchJSON is a JSON patch (RFC 6902).
+	OverridePatchJSON OverridePatchType = "JSON"
+)
+
+// CronJobTemplateOverride patches the CronJob template for the nodes matching its selector.
+type CronJobTemplateOverride struct {
+	// Name of the override, recorded on the CronJobs it applies to.
+	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
+
+	// Selector is a label query over the nodes the override applies to.
+	Selector metav1.LabelSelector `json:"selector" protobuf:"bytes,2,opt,name=selector"`
+
+	// PatchType is the type of the patch. Can be "StrategicMerge" or "JSON". Defaults to StrategicMerge.
+	// +optional
+	// +kubebuilder:default=StrategicMerge
+	PatchType OverridePatchType `json:"patchType,omitempty" protobuf:"bytes,3,opt,name=patchType,casttype=OverridePatchType"`
+
+	// Patch is applied to the cronJobTemplate, i.e. to an object with metadata and spec fields.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Patch runtime.RawExtension `json:"patch" protobuf:"bytes,4,opt,name=patch"`
+}
+
 // CronSetSpec defines the desired state of CronSet
 type CronSetSpec struct {
+	// Selector is a label query over nodes that should run a CronJob created from this CronSet.
+	// It supports matchLabels as well as matchExpressions (In, NotIn, Exists, DoesNotExist) and is
+	// combined with the nodeSelector of the pod template, so a node must satisfy both.
+	// If empty, every node that matches the pod template's nodeSelector is selected.
+	// More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#label-selectors
+	// +optional
 	Selector *metav1.LabelSelector `json:"selector,omitempty" protobuf:"bytes,1,opt,name=selector"`
 
 	CronJobTemplate CronJobTemplateSpec `json:"cronJobTemplate,omitempty" protobuf:"bytes,2,opt,name=cronJobTemplate"`
+
+	// DefaultTolerations is the policy of the tolerations added to the pod template of every
+	// generated CronJob. The tolerations are also taken into account when selecting nodes.
+	// Defaults to None.
+	// +optional
+	// +kubebuilder:default=None
+	DefaultTolerations DefaultTolerationsPolicy `json:"defaultTolerations,omitempty" protobuf:"bytes,3,opt,name=defaultTolerations,casttype=DefaultTolerationsPolicy"`
+
+	// NodeHealthPolicy describes how the CronJobs of cordoned (spec.unschedulable) and NotReady
+	// nodes are handled. When the policy is Suspend or Remove, the node.kubernetes.io/unschedulable,
+	// not-ready and unreachable taints no longer exclude a node, because the policy takes care of it.
+	// +optional
+	NodeHealthPolicy *NodeHealthPolicy `json:"nodeHealthPolicy,omitempty" protobuf:"bytes,4,opt,name=nodeHealthPolicy"`
+
+	// LabelPropagationPolicy decides whether the labels of the CronSet are copied to its CronJobs.
+	// The labels and annotations of cronJobTemplate.metadata are always applied and take precedence
+	// over the labels of the CronSet. Defaults to All.
+	// +optional
+	// +kubebuilder:default=All
+	LabelPropagationPolicy LabelPropagationPolicy `json:"labelPropagationPolicy,omitempty" protobuf:"bytes,5,opt,name=labelPropagationPolicy,casttype=LabelPropagationPolicy"`
+
+	// UpdateStrategy describes how a change of the CronJob template is rolled out to the existing
+	// CronJobs. CronJobs of new nodes are always created from the current template.
+	// If unset, every CronJob is updated at once.
+	// +optional
+	UpdateStrategy *CronSetUpdateStrategy `json:"updateStrategy,omitempty" protobuf:"bytes,6,opt,name=updateStrategy"`
+
+	// RevisionHistoryLimit is the number of old ControllerRevisions kept to allow rollbacks.
+	// Revisions still used by a CronJob are never deleted. Defaults to 10.
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty" protobuf:"varint,7,opt,name=revisionHistoryLimit"`
+
+	// RollbackTo requests a rollback to a previous revision. The controller replaces the CronJob
+	// template and the default tolerations with the ones of the revision, then clears this field.
+	// The rollback is then rolled out according to the update strategy.
+	// +optional
+	RollbackTo *CronSetRollback `json:"rollbackTo,omitempty" protobuf:"bytes,8,opt,name=rollbackTo"`
+
+	// Stagger spreads the schedules of the CronJobs across the nodes, so that they don't all
+	// run at the same time. Independently of it, the schedule of the template may use H tokens
+	// (H, H(a-b), H/n, H(a-b)/n) which are replaced by a value derived from the node identifier.
+	// +optional
+	Stagger *Stagger `json:"stagger,omitempty" protobuf:"bytes,9,opt,name=stagger"`
+
+	// Overrides patch the CronJob template for the nodes matching their selector, e.g. to set
+	// other resources or images on some node pools. Only the first matching override applies.
+	// The node selection always uses the template without overrides.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Overrides []CronJobTemplateOverride `json:"overrides,omitempty" protobuf:"bytes,10,rep,name=overrides"`
+
+	// TerminationProtection delays the drain and the deletion of a node while a Job of the
+	// CronSet is running on it: the pods of the Job are covered by a PodDisruptionBudget and the
+	// node gets a finalizer, until the Job finishes or the maximum delay passes.
+	// If unset, nodes are not protected.
+	// +optional
+	TerminationProtection *TerminationProtection `json:"terminationProtection,omitempty" protobuf:"bytes,11,opt,name=terminationProtection"`
+
+	// ScaleDownProtection keeps the cluster autoscaler and Karpenter from removing the nodes running
+	// a Job of the CronSet, by annotating either the pods of the Jobs or the nodes. Defaults to None.
+	// +optional
+	// +kubebuilder:default=None
+	ScaleDownProtection ScaleDownProtectionPolicy `json:"scaleDownProtection,omitempty" protobuf:"bytes,12,opt,name=scaleDownProtection,casttype=ScaleDownProtectionPolicy"`
+
+	// OnNodeRemoval is a Job launched on a node leaving the CronSet, e.g. deleted, cordoned or
+	// relabeled, to run final work such as flushing local buffers. The CronJob of the node is
+	// deleted once the Job finished, or once the node is gone.
+	// +optional
+	OnNodeRemoval *NodeRemovalHook `json:"onNodeRemoval,omitempty" protobuf:"bytes,13,opt,name=onNodeRemoval"`
+
+	// NodeRemovalGracePeriod is how long the CronJob of a node which left the CronSet is kept
+	// suspended before it is deleted, so that a node flapping out of the selection keeps its
+	// CronJob and its Job history. If unset, the CronJob is deleted right away.
+	// +optional
+	NodeRemovalGracePeriod *metav1.Duration `json:"nodeRemovalGracePeriod,omitempty" protobuf:"bytes,14,opt,name=nodeRemovalGracePeriod"`
+
+	// DeletionPolicy decides whether the CronJobs are deleted along with the CronSet, or orphaned
+	// so that a CronSet recreated with the same name takes them back. Defaults to Delete.
+	// +optional
+	// +kubebuilder:default=Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty" protobuf:"bytes,15,opt,name=deletionPolicy,casttype=DeletionPolicy"`
+
+	// Adoption takes over pre-existing per-node CronJobs, e.g. created by hand before the CronSet.
+	// A CronJob with the canonical name of its node is adopted in place; any other one is replaced
+	// by the CronJob of the node, which takes over its Jobs.
+	// +optional
+	Adoption *CronJobAdoption `json:"adoption,omitempty" protobuf:"bytes,16,opt,name=adoption"`
 }
 
+// ExcludedNodeReason is the reason why a selected node doesn't run a CronJob.
+type ExcludedNodeReason string
+
+const (
+	// ExcludedNodeReasonUntoleratedTaint means the node has a NoSchedule or NoExecute taint
+	// which is not tolerated by the pod template.
+	ExcludedNodeReasonUntoleratedTaint ExcludedNodeReason = "UntoleratedTaint"
+
+	// ExcludedNodeReasonNodeCordoned means the node is cordoned and the node health policy is Remove.
+	ExcludedNodeReasonNodeCordoned ExcludedNodeReason = "NodeCordoned"
+
+	// ExcludedNodeReasonNodeNotReady means the node has been NotReady for longer than the grace
+	// period and the node health policy is Remove.
+	ExcludedNodeReasonNodeNotReady ExcludedNodeReason = "NodeNotReady"
+
+	// ExcludedNodeReasonOptedOut means the node opted out of the CronSet with the
+	// cronset.grasse.io/exclude annotation.
+	ExcludedNodeReasonOptedOut ExcludedNodeReason = "OptedOut"
+)
+
+// ExcludedNode describes a node which is selected by the CronSet but doesn't run its CronJob.
+type ExcludedNode struct {
+	// Name of the node.
+	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
+
+	// Reason why the node is excluded.
+	Reason ExcludedNodeReason `json:"reason" protobuf:"bytes,2,opt,name=reason,casttype=ExcludedNodeReason"`
+
+	// Taint of the node which is not tolerated by the pod template.
+	// +optional
+	Taint *corev1.Taint `json:"taint,omitempty" protobuf:"bytes,3,opt,name=taint"`
+}
+
+// CronSetNodeStatus describes the CronJob of a single node.
+type CronSetNodeStatus struct {
+	// NodeName is the name of the node.
+	NodeName string `json:"nodeName" protobuf:"bytes,1,opt,name=nodeName"`
+
+	// CronJobName is the name of the CronJob of the node.
+	// +optional
+	CronJobName string `json:"cronJobName,omitempty" protobuf:"bytes,2,opt,name=cronJobName"`
+
+	// TemplateHash is the hash of the CronSet template the CronJob was generated from.
+	// +optional
+	TemplateHash string `json:"templateHash,omitempty" protobuf:"bytes,3,opt,name=templateHash"`
+
+	// LastErrorReason is a machine-readable reason of the last failure to apply the CronJob
+	// on the node. Empty when the last apply succeeded.
+	// +optional
+	LastErrorReason string `json:"lastErrorReason,omitempty" protobuf:"bytes,4,opt,name=lastErrorReason"`
+
+	// LastErrorMessage is a human-readable message of the last failure to apply the CronJob.
+	// +optional
+	LastErrorMessage string `json:"lastErrorMessage,omitempty" protobuf:"bytes,5,opt,name=lastErrorMessage"`
+
+	// LastScheduleTime is the last time a job of the CronJob was scheduled.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty" protobuf:"bytes,6,opt,name=lastScheduleTime"`
+
+	// LastSuccessfulTime is the last time a job of the CronJob successfully completed.
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty" protobuf:"bytes,7,opt,name=lastSuccessfulTime"`
+
+	// PendingDeletionTime is when the suspended CronJob of a node which left the CronSet will be
+	// deleted, unless the node comes back before.
+	// +optional
+	PendingDeletionTime *metav1.Time `json:"pendingDeletionTime,omitempty" protobuf:"bytes,8,opt,name=pendingDeletionTime"`
+
+	// AdoptedFrom is the name of the pre-existing CronJob adopted for the node.
+	// +optional
+	AdoptedFrom string `json:"adoptedFrom,omitempty" protobuf:"bytes,9,opt,name=adoptedFrom"`
+}
+
+// CanaryPhase is the phase of the canary of a template change.
+type CanaryPhase string
+
+const (
+	// CanaryPhaseProgressing means the canary CronJobs haven't all succeeded yet.
+	CanaryPhaseProgressing CanaryPhase = "Progressing"
+
+	// CanaryPhasePromoted means the canary succeeded and the template is rolled out to every node.
+	CanaryPhasePromoted CanaryPhase = "Promoted"
+
+	// CanaryPhaseFailed means a canary job failed and every node runs the previous template.
+	CanaryPhaseFailed CanaryPhase = "Failed"
+)
+
+// CanaryStatus describes the canary of the last template change.
+type CanaryStatus struct {
+	// Revision is the name of the ControllerRevision under canary.
+	Revision string `json:"revision" protobuf:"bytes,1,opt,name=revision"`
+
+	// PreviousRevision is the name of the ControllerRevision the nodes are kept on, and rolled back
+	// to when the canary fails.
+	PreviousRevision string `json:"previousRevision" protobuf:"bytes,2,opt,name=previousRevision"`
+
+	// Phase of the canary.
+	Phase CanaryPhase `json:"phase" protobuf:"bytes,3,opt,name=phase,casttype=CanaryPhase"`
+
+	// Message is a human-readable description of the phase.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,4,opt,name=message"`
+
+	// StartTime is when the canary started. The timeout of the canary counts from it.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty" protobuf:"bytes,5,opt,name=startTime"`
+}
+
+// Condition types of a CronSet.
+const (
+	// CronSetAvailable means a CronJob exists on every eligible node.
+	CronSetAvailable = "Available"
+
+	// CronSetProgressing means the controller is creating, updating or deleting CronJobs
+	// to reach the desired state.
+	CronSetProgressing = "Progressing"
+
+	// CronSetDegraded means the controller fails to apply the CronSet on some nodes, the CronSet
+	// spec is invalid, or the canary of the template failed or has no node.
+	CronSetDegraded = "Degraded"
+
+	// CronSetNoEligibleNodes means no node is eligible to run a CronJob of the CronSet.
+	CronSetNoEligibleNodes = "NoEligibleNodes"
+
+	// CronSetCanaryPromoted means the canary of the last template change succeeded. It is only set
+	// while the update strategy has a canary.
+	CronSetCanaryPromoted = "CanaryPromoted"
+)
+
 // CronSetStatus defines the observed state of CronSet
 type CronSetStatus struct {
 	CurrentNumberScheduled int32 `json:"currentNumberScheduled" protobuf:"varint,1,opt,name=currentNumberScheduled"`
@@ -50,6 +513,53 @@ type CronSetStatus struct {
 	NumberMisscheduled int32 `json:"numberMisscheduled" protobuf:"varint,2,opt,name=numberMisscheduled"`
 
 	DesiredNumberScheduled int32 `json:"desiredNumberScheduled" protobuf:"varint,3,opt,name=desiredNumberScheduled"`
+
+	// ExcludedNodes lists the selected nodes that don't run a CronJob, e.g. because one of
+	// their taints is not tolerated by the pod template.
+	// +optional
+	ExcludedNodes []ExcludedNode `json:"excludedNodes,omitempty" protobuf:"bytes,4,rep,name=excludedNodes"`
+
+	// ObservedGeneration is the most recent generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,5,opt,name=observedGeneration"`
+
+	// Conditions represent the latest available observations of the CronSet's state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,6,rep,name=conditions"`
+
+	// Nodes describes the CronJob of every node, failed nodes first and then by node name.
+	// The list is limited to 256 entries on large clusters.
+	// +optional
+	// +listType=map
+	// +listMapKey=nodeName
+	// +kubebuilder:validation:MaxItems=256
+	Nodes []CronSetNodeStatus `json:"nodes,omitempty" protobuf:"bytes,7,rep,name=nodes"`
+
+	// UpdatedNumberScheduled is the number of CronJobs generated from the current template.
+	// +optional
+	UpdatedNumberScheduled int32 `json:"updatedNumberScheduled,omitempty" protobuf:"varint,8,opt,name=updatedNumberScheduled"`
+
+	// NumberUnavailable is the number of CronJobs updated to the current template whose next run
+	// hasn't succeeded yet.
+	// +optional
+	NumberUnavailable int32 `json:"numberUnavailable,omitempty" protobuf:"varint,9,opt,name=numberUnavailable"`
+
+	// CurrentRevision is the name of the ControllerRevision of the current CronJob template.
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty" protobuf:"bytes,10,opt,name=currentRevision"`
+
+	// Canary describes the canary of the last template change, when the update strategy has one.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty" protobuf:"bytes,11,opt,name=canary"`
+
+	// CollisionCount is the number of template hash collisions of the CronSet. It is mixed into the
+	// template hash, so that a colliding template gets a revision of its own.
+	// +optional
+	CollisionCount *int32 `json:"collisionCount,omitempty" protobuf:"varint,12,opt,name=collisionCount"`
 }
 
 //+kubebuilder:object:root=true
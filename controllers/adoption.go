@@ -0,0 +1,190 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// AdoptedFromAnnotation records the name of the pre-existing CronJob adopted for the node.
+const AdoptedFromAnnotation = "grasse.io/adopted-from"
+
+// adoptionSelector returns the selector of the CronJobs to adopt, nil without adoption.
+func adoptionSelector(cronSet *batchv1alpha1.CronSet) (labels.Selector, error) {
+	if cronSet.Spec.Adoption == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&cronSet.Spec.Adoption.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid adoption.selector: %w", err)
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("adoption.selector must not be empty")
+	}
+	return selector, nil
+}
+
+// pinnedNodeName returns the eligible node the pods of the CronJob are pinned to, with nodeName or
+// a kubernetes.io/hostname nodeSelector.
+func pinnedNodeName(cronJob *batchv1.CronJob, eligibleNodes []corev1.Node) string {
+	podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+	hostname, hasHostname := podSpec.NodeSelector[corev1.LabelHostname]
+	for _, node := range eligibleNodes {
+		if podSpec.NodeName != "" {
+			if node.Name == podSpec.NodeName {
+				return node.Name
+			}
+		} else if hasHostname && node.Labels[corev1.LabelHostname] == hostname {
+			return node.Name
+		}
+	}
+	return ""
+}
+
+// findAdoptionCandidates returns the CronJobs to adopt, keyed by the eligible node they are pinned
+// to. CronJobs with a controller are skipped, and so are all but the first CronJob by name of a node.
+func (r *CronSetReconciler) findAdoptionCandidates(ctx context.Context, cronSet *batchv1alpha1.CronSet, selector labels.Selector,
+	eligibleNodes []corev1.Node) (map[string]*batchv1.CronJob, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	cronJobList := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronJobList,
+		client.InNamespace(cronSet.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	); err != nil {
+		return nil, err
+	}
+	sort.Slice(cronJobList.Items, func(i, j int) bool {
+		return cronJobList.Items[i].Name < cronJobList.Items[j].Name
+	})
+
+	candidates := make(map[string]*batchv1.CronJob)
+	for i := range cronJobList.Items {
+		cronJob := &cronJobList.Items[i]
+		if metav1.GetControllerOf(cronJob) != nil {
+			continue
+		}
+		nodeName := pinnedNodeName(cronJob, eligibleNodes)
+		if nodeName == "" {
+			continue
+		}
+		if adopted, ok := candidates[nodeName]; ok {
+			r.Log.Info("Skip adoption of a second CronJob of the node", "cronset", cronSet.Name, "cronjob", cronJob.Name, "node", nodeName, "adopted", adopted.Name)
+			continue
+		}
+		candidates[nodeName] = cronJob
+	}
+	return candidates, nil
+}
+
+// adoptCronJob takes ownership of a CronJob with the canonical name of its node. It is updated to the
+// template of the CronSet afterwards like any other CronJob.
+func (r *CronSetReconciler) adoptCronJob(ctx context.Context, cronSet *batchv1alpha1.CronSet, cronJob *batchv1.CronJob, nodeName string, events *eventAggregator) error {
+	patch := client.MergeFromWithOptions(cronJob.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	if err := controllerutil.SetControllerReference(cronSet, cronJob, r.Scheme); err != nil {
+		return err
+	}
+	if cronJob.Labels == nil {
+		cronJob.Labels = make(map[string]string)
+	}
+	cronJob.Labels[OwnerLabel] = truncateLabelValue(cronSet.Name)
+	cronJob.Labels[OwnerUIDLabel] = string(cronSet.UID)
+	if cronJob.Annotations == nil {
+		cronJob.Annotations = make(map[string]string)
+	}
+	cronJob.Annotations[AdoptedFromAnnotation] = cronJob.Name
+	if err := r.Patch(ctx, cronJob, patch); err != nil {
+		return err
+	}
+	events.add(corev1.EventTypeNormal, EventReasonAdopted, "Adopted CronJob %s of node %s", cronJob.Name, nodeName)
+	r.Log.Info("Adopt CronJob", "cronset", cronSet.Name, "cronjob", cronJob.Name, "node", nodeName)
+	return nil
+}
+
+// suspendReplacedCronJob suspends a differently named CronJob of the node before the CronJob of the
+// CronSet is applied, so that both don't run on the node if the replacement doesn't go through.
+func (r *CronSetReconciler) suspendReplacedCronJob(ctx context.Context, cronSet *batchv1alpha1.CronSet, cronJob *batchv1.CronJob) error {
+	if ptr.Deref(cronJob.Spec.Suspend, false) {
+		return nil
+	}
+	patch := client.MergeFromWithOptions(cronJob.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	cronJob.Spec.Suspend = ptr.To(true)
+	if err := r.Patch(ctx, cronJob, patch); err != nil {
+		return err
+	}
+	r.Log.Info("Suspend CronJob to replace", "cronset", cronSet.Name, "cronjob", cronJob.Name)
+	return nil
+}
+
+// replaceCronJob replaces a differently named CronJob of the node with the CronJob of the CronSet,
+// which must exist already: the Jobs of the old CronJob, suspended beforehand, are handed over to the
+// new one, so that they keep running and stay in its history, then the old CronJob is deleted.
+func (r *CronSetReconciler) replaceCronJob(ctx context.Context, cronSet *batchv1alpha1.CronSet, oldCronJob *batchv1.CronJob, cronJobName string,
+	nodeName string, events *eventAggregator) error {
+	cronJob := &batchv1.CronJob{}
+	if err := r.Get(ctx, types.NamespacedName{Name: cronJobName, Namespace: cronSet.Namespace}, cronJob); err != nil {
+		return err
+	}
+	if cronJob.Annotations[AdoptedFromAnnotation] != oldCronJob.Name {
+		patch := client.MergeFrom(cronJob.DeepCopy())
+		if cronJob.Annotations == nil {
+			cronJob.Annotations = make(map[string]string)
+		}
+		cronJob.Annotations[AdoptedFromAnnotation] = oldCronJob.Name
+		if err := r.Patch(ctx, cronJob, patch); err != nil {
+			return err
+		}
+	}
+
+	jobsByCronJob, err := r.listJobsByCronJob(ctx, []batchv1.CronJob{*oldCronJob})
+	if err != nil {
+		return err
+	}
+	controllerRef := metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob"))
+	for _, job := range jobsByCronJob[oldCronJob.UID] {
+		patch := client.MergeFromWithOptions(job.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		for i := range job.OwnerReferences {
+			if job.OwnerReferences[i].UID == oldCronJob.UID {
+				job.OwnerReferences[i] = *controllerRef
+			}
+		}
+		if err := r.Patch(ctx, &job, patch); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Delete(ctx, oldCronJob, client.Preconditions{UID: &oldCronJob.UID}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	events.add(corev1.EventTypeNormal, EventReasonAdopted, "Replaced CronJob %s of node %s with %s", oldCronJob.Name, nodeName, cronJobName)
+	r.Log.Info("Replace adopted CronJob", "cronset", cronSet.Name, "cronjob", oldCronJob.Name, "node", nodeName, "replacement", cronJobName)
+	return nil
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// defaultCanaryTimeout is used when the canary doesn't set a timeout.
+const defaultCanaryTimeout = 24 * time.Hour
+
+// jobControllerUIDField indexes the jobs by the UID of the CronJob controlling them.
+const jobControllerUIDField = ".metadata.controllerCronJobUID"
+
+// canaryState is the outcome of evaluating the canary of a template change.
+type canaryState struct {
+	status *batchv1alpha1.CanaryStatus
+	// nodes are the canary nodes while the canary is progressing.
+	nodes map[string]bool
+	// previousCronSet holds the previous template, which every node runs once the canary failed.
+	previousCronSet *batchv1alpha1.CronSet
+	// requeueAfter is set while the canary is progressing, to when it times out.
+	requeueAfter time.Duration
+}
+
+// progressingNodes returns the canary nodes while the canary is progressing, nil otherwise.
+func (c *canaryState) progressingNodes() map[string]bool {
+	if c == nil || c.status.Phase != batchv1alpha1.CanaryPhaseProgressing {
+		return nil
+	}
+	return c.nodes
+}
+
+func (c *canaryState) failed() bool {
+	return c != nil && c.status.Phase == batchv1alpha1.CanaryPhaseFailed
+}
+
+// nodesMissing reports whether the canary is progressing without any canary node.
+func (c *canaryState) nodesMissing() bool {
+	return c != nil && c.status.Phase == batchv1alpha1.CanaryPhaseProgressing && len(c.nodes) == 0
+}
+
+func (c *canaryState) requeue() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.requeueAfter
+}
+
+func canaryTimeout(canary *batchv1alpha1.CanaryStrategy) time.Duration {
+	if canary.Timeout == nil {
+		return defaultCanaryTimeout
+	}
+	return canary.Timeout.Duration
+}
+
+func canaryStrategy(cronSet *batchv1alpha1.CronSet) *batchv1alpha1.CanaryStrategy {
+	strategy := cronSet.Spec.UpdateStrategy
+	if strategy == nil || strategy.Type == batchv1alpha1.OnDeleteCronSetStrategyType {
+		return nil
+	}
+	return strategy.Canary
+}
+
+// selectCanaryNodes returns the names of the canary nodes of the CronSet, in order: the eligible
+// nodes matching the canary selector, or else the first nodes by name.
+func selectCanaryNodes(cronSet *batchv1alpha1.CronSet, eligibleNodes []corev1.Node) ([]string, error) {
+	canary := canaryStrategy(cronSet)
+	if canary == nil {
+		return nil, nil
+	}
+
+	var nodeNames []string
+	if canary.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(canary.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid updateStrategy.canary.selector: %w", err)
+		}
+		for _, node := range eligibleNodes {
+			if selector.Matches(labels.Set(node.Labels)) {
+				nodeNames = append(nodeNames, node.Name)
+			}
+		}
+		sort.Strings(nodeNames)
+		return nodeNames, nil
+	}
+
+	for _, node := range eligibleNodes {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	sort.Strings(nodeNames)
+	canaryNodeCount := ptr.Deref(canary.Nodes, intstr.FromInt32(1))
+	count, err := intstr.GetScaledValueFromIntOrPercent(&canaryNodeCount, len(nodeNames), true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid updateStrategy.canary.nodes: %w", err)
+	}
+	return nodeNames[:min(max(count, 1), len(nodeNames))], nil
+}
+
+// evaluateCanary tracks the canary of the current revision of the CronSet. A canary starts when the
+// current revision changes, and is promoted once every canary CronJob completed enough jobs since it
+// was updated, or right away when the template is suspended. It fails as soon as one of these jobs
+// fails, or when it isn't promoted within its timeout. The decision is recorded with an event.
+func (r *CronSetReconciler) evaluateCanary(ctx context.Context, cronSet *batchv1alpha1.CronSet, canaryNodes []string,
+	cronJobNames map[string]string, existingCronJobs map[string]*batchv1.CronJob, jobsByCronJob map[types.UID][]batchv1.Job,
+	currentRevision string, now time.Time) (*canaryState, error) {
+	canary := canaryStrategy(cronSet)
+	if canary == nil {
+		return nil, nil
+	}
+
+	status := cronSet.Status.Canary.DeepCopy()
+	if status == nil || status.Revision != currentRevision {
+		previousRevision := cronSet.Status.CurrentRevision
+		if status != nil && status.Phase != batchv1alpha1.CanaryPhasePromoted {
+			// The other nodes still run the previous revision of the unfinished canary.
+			previousRevision = status.PreviousRevision
+		}
+		if previousRevision == "" || previousRevision == currentRevision {
+			return nil, nil
+		}
+		status = &batchv1alpha1.CanaryStatus{
+			Revision:         currentRevision,
+			PreviousRevision: previousRevision,
+			Phase:            batchv1alpha1.CanaryPhaseProgressing,
+		}
+	}
+	if status.StartTime == nil {
+		status.StartTime = &metav1.Time{Time: now}
+	}
+	state := &canaryState{status: status, nodes: make(map[string]bool)}
+
+	if status.Phase == batchv1alpha1.CanaryPhaseProgressing {
+		for _, nodeName := range canaryNodes {
+			state.nodes[nodeName] = true
+		}
+		successfulRuns := max(canary.SuccessfulRuns, 1)
+		succeededNodes, failure := evaluateCanaryRuns(cronSet, canaryNodes, cronJobNames, existingCronJobs, jobsByCronJob, successfulRuns)
+		progress := fmt.Sprintf("%d/%d canary node(s) completed %d successful run(s)", succeededNodes, len(canaryNodes), successfulRuns)
+		if len(canaryNodes) == 0 {
+			progress = "No eligible node matches the canary"
+		}
+		timeout := canaryTimeout(canary)
+		remaining := status.StartTime.Add(timeout).Sub(now)
+		switch {
+		case failure == "" && ptr.Deref(cronSet.Spec.CronJobTemplate.Spec.Suspend, false):
+			status.Phase = batchv1alpha1.CanaryPhasePromoted
+			status.Message = "The template is suspended"
+			r.Recorder.Eventf(cronSet, corev1.EventTypeNormal, EventReasonCanaryPromoted, "Promoted revision %s, whose template is suspended",
+				status.Revision)
+		case failure == "" && len(canaryNodes) > 0 && succeededNodes == len(canaryNodes):
+			status.Phase = batchv1alpha1.CanaryPhasePromoted
+			status.Message = fmt.Sprintf("%d canary node(s) succeeded", len(canaryNodes))
+			r.Recorder.Eventf(cronSet, corev1.EventTypeNormal, EventReasonCanaryPromoted, "Promoted revision %s after %d canary node(s) succeeded",
+				status.Revision, len(canaryNodes))
+		case failure == "" && remaining > 0:
+			status.Message = progress
+			state.requeueAfter = remaining
+		default:
+			if failure == "" {
+				failure = fmt.Sprintf("Timed out after %s: %s", timeout, progress)
+			}
+			status.Phase = batchv1alpha1.CanaryPhaseFailed
+			status.Message = failure
+			r.Recorder.Eventf(cronSet, corev1.EventTypeWarning, EventReasonCanaryFailed, "Canary of revision %s failed, rolling back to revision %s: %s",
+				status.Revision, status.PreviousRevision, failure)
+		}
+	}
+
+	if status.Phase == batchv1alpha1.CanaryPhaseFailed {
+		spec, err := r.getRevisionTemplate(ctx, cronSet, status.PreviousRevision)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the previous revision %s: %w", status.PreviousRevision, err)
+		}
+		state.previousCronSet = withTemplate(cronSet, spec)
+	}
+	return state, nil
+}
+
+// evaluateCanaryRuns inspects the jobs the canary CronJobs created since they were updated to the
+// current template. It returns the number of canary nodes with enough successful jobs, and a
+// description of the first failed job if any.
+func evaluateCanaryRuns(cronSet *batchv1alpha1.CronSet, canaryNodes []string, cronJobNames map[string]string,
+	existingCronJobs map[string]*batchv1.CronJob, jobsByCronJob map[types.UID][]batchv1.Job, successfulRuns int32) (int, string) {
+	templateHash := computeTemplateHash(cronSet)
+	succeededNodes := 0
+	for _, nodeName := range canaryNodes {
+		cronJob, ok := existingCronJobs[cronJobNames[nodeName]]
+		if !ok || cronJob.Labels[TemplateHashLabel] != templateHash {
+			continue
+		}
+		since := templateUpdateTime(cronJob)
+		succeeded := int32(0)
+		for _, job := range jobsByCronJob[cronJob.UID] {
+			if job.CreationTimestamp.Time.Before(since) {
+				continue
+			}
+			switch {
+			case hasJobCondition(&job, batchv1.JobFailed):
+				return 0, fmt.Sprintf("Job %s of node %s failed", job.Name, nodeName)
+			case hasJobCondition(&job, batchv1.JobComplete):
+				succeeded++
+			}
+		}
+		if succeeded >= successfulRuns {
+			succeededNodes++
+		}
+	}
+	return succeededNodes, ""
+}
+
+// templateUpdateTime returns when the CronJob was updated to its template, or else created.
+func templateUpdateTime(cronJob *batchv1.CronJob) time.Time {
+	if updatedAt, err := time.Parse(time.RFC3339, cronJob.Annotations[TemplateUpdatedAnnotation]); err == nil {
+		return updatedAt
+	}
+	return cronJob.CreationTimestamp.Time
+}
+
+// indexJobByControllerUID returns the UID of the CronJob controlling the job, for jobControllerUIDField.
+func indexJobByControllerUID(job client.Object) []string {
+	owner := metav1.GetControllerOf(job)
+	if owner == nil || owner.Kind != "CronJob" {
+		return nil
+	}
+	return []string{string(owner.UID)}
+}
+
+// listJobsByCronJob returns the jobs controlled by the CronJobs, grouped by CronJob UID.
+func (r *CronSetReconciler) listJobsByCronJob(ctx context.Context, cronJobs []batchv1.CronJob) (map[types.UID][]batchv1.Job, error) {
+	jobsByCronJob := make(map[types.UID][]batchv1.Job, len(cronJobs))
+	for _, cronJob := range cronJobs {
+		jobList := &batchv1.JobList{}
+		if err := r.List(ctx, jobList,
+			client.InNamespace(cronJob.Namespace),
+			client.MatchingFields{jobControllerUIDField: string(cronJob.UID)},
+		); err != nil {
+			return nil, err
+		}
+		jobsByCronJob[cronJob.UID] = jobList.Items
+	}
+	return jobsByCronJob, nil
+}
+
+func hasJobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// findCronSetForJob maps a job event to the CronSet owning the CronJob of the job, or owning the job
+// itself for node removal hooks.
+func (r *CronSetReconciler) findCronSetForJob(ctx context.Context, job client.Object) []reconcile.Request {
+	owner := metav1.GetControllerOf(job)
+	if owner != nil && owner.Kind == "CronSet" && owner.APIVersion == batchv1alpha1.GroupVersion.String() {
+		return []reconcile.Request{{
+			NamespacedName: types.NamespacedName{Name: owner.Name, Namespace: job.GetNamespace()},
+		}}
+	}
+	if owner == nil || owner.Kind != "CronJob" {
+		return nil
+	}
+	cronJob := &batchv1.CronJob{}
+	if err := r.Get(ctx, types.NamespacedName{Name: owner.Name, Namespace: job.GetNamespace()}, cronJob); err != nil {
+		return nil
+	}
+	cronSetOwner := metav1.GetControllerOf(cronJob)
+	if cronSetOwner == nil || cronSetOwner.Kind != "CronSet" || cronSetOwner.APIVersion != batchv1alpha1.GroupVersion.String() {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Name: cronSetOwner.Name, Namespace: cronJob.Namespace},
+	}}
+}
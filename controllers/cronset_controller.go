@@ -18,9 +18,8 @@ package controllers
 
 import (
 	"context"
-	"k8s.io/apimachinery/pkg/labels"
-	"os"
-	"strings"
+	"maps"
+	"time"
 
 	"k8s.io/apimachinery/pkg/types"
 
@@ -28,9 +27,12 @@ import (
 	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -39,63 +41,66 @@ import (
 )
 
 const (
-	OwnerLabel            = "grasse.io/owner"
-	NodeIdentificationKey = "NODE_IDENTIFICATION_KEY"
+	OwnerLabel         = "grasse.io/owner"
+	OwnerUIDLabel      = "grasse.io/owner-uid"
+	NodeLabel          = "grasse.io/node"
+	NodeNameAnnotation = "grasse.io/node-name"
+	TemplateHashLabel  = "grasse.io/template-hash"
+	// OverrideAnnotation records the name of the template override applied to a CronJob.
+	OverrideAnnotation = "grasse.io/override"
+	// TemplateUpdatedAnnotation records when an existing CronJob was updated to a new template.
+	TemplateUpdatedAnnotation = "grasse.io/template-updated-at"
+	NodeIdentificationKey     = "NODE_IDENTIFICATION_KEY"
 )
 
 // CronSetReconciler reconciles a CronSet object
 type CronSetReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 type CronSetStatus struct {
 	CurrentDependentCronJobCount int32
 	MisScheduledJobCount         int32
 	DesiredScheduledJobCount     int32
+	ExcludedNodes                []batchv1alpha1.ExcludedNode
+	// ScheduledCronJobCount is the number of eligible nodes with an applied CronJob. Unlike
+	// CurrentDependentCronJobCount, it leaves out the CronJobs of departed nodes pending deletion.
+	ScheduledCronJobCount int32
+	// NodeFailures maps the nodes whose CronJob couldn't be applied to the failure.
+	NodeFailures map[string]nodeFailure
+	// NodeStatuses describes the CronJob of every node.
+	NodeStatuses []batchv1alpha1.CronSetNodeStatus
+	// ChangedCronJobCount is the number of CronJobs created, updated or deleted in the reconcile.
+	ChangedCronJobCount int32
+	// UpdatedCronJobCount is the number of CronJobs on the current template.
+	UpdatedCronJobCount int32
+	// UnavailableCronJobCount is the number of updated CronJobs awaiting a successful run.
+	UnavailableCronJobCount int32
+	// RolloutInProgress is true while a rolling update of the template isn't complete.
+	RolloutInProgress bool
+	// CurrentRevision is the name of the ControllerRevision of the current template.
+	CurrentRevision string
+	// Canary describes the canary of the current template, if any.
+	Canary *batchv1alpha1.CanaryStatus
+	// CanaryNodesMissing is true while the canary is progressing without any canary node.
+	CanaryNodesMissing bool
 }
 
 func (r *CronSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &batchv1.Job{}, jobControllerUIDField, indexJobByControllerUID); err != nil {
+		return err
+	}
 	if err := ctrl.NewControllerManagedBy(mgr).
 		For(&batchv1alpha1.CronSet{}).
 		Owns(&batchv1.CronJob{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
 		Watches(&corev1.Node{},
-			handler.TypedEnqueueRequestsFromMapFunc[client.Object, reconcile.Request](func(ctx context.Context, node client.Object) []reconcile.Request {
-				nodeLabels := node.GetLabels()
-				r.Log.Info("Node Event", "Node", node.GetName(), "Node Labels", nodeLabels)
-
-				var cronSetObjs batchv1alpha1.CronSetList
-				_ = mgr.GetClient().List(ctx, &cronSetObjs)
-
-				var requests []reconcile.Request
-
-			CronSetLoop:
-				for _, cronSet := range cronSetObjs.Items {
-					nodeSelector := cronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.NodeSelector
-					r.Log.Info("Check CronSet for Node Event", "CronSet name", cronSet.Name, "nodeSelector", nodeSelector)
-
-					if len(nodeSelector) != 0 {
-						for key, value := range nodeSelector {
-							nodeLabel, exists := nodeLabels[key]
-							if !exists || nodeLabel != value {
-								r.Log.Info("CronSet's nodeSelector doesn't match event trigger node's label", "Node", node.GetName(), "CronSet", cronSet.Name)
-								continue CronSetLoop
-							}
-						}
-					}
-
-					r.Log.Info("Add to request CronSet due to node event occured", "Node", node.GetName(), "CronSet", cronSet.Name)
-					requests = append(requests, reconcile.Request{
-						NamespacedName: types.NamespacedName{
-							Name:      cronSet.Name,
-							Namespace: cronSet.Namespace,
-						},
-					})
-				}
-
-				return requests
-			})).
+			handler.TypedEnqueueRequestsFromMapFunc[client.Object, reconcile.Request](r.findCronSetsForNode)).
+		Watches(&batchv1.Job{},
+			handler.TypedEnqueueRequestsFromMapFunc[client.Object, reconcile.Request](r.findCronSetForJob)).
 		Complete(r); err != nil {
 		return err
 	}
@@ -103,11 +108,53 @@ func (r *CronSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return nil
 }
 
+// findCronSetsForNode maps a node event to the CronSets whose node selection matches the node.
+// Taints are evaluated in Reconcile, so a CronSet is enqueued whenever the taints of one of its
+// selected nodes change.
+func (r *CronSetReconciler) findCronSetsForNode(ctx context.Context, node client.Object) []reconcile.Request {
+	nodeLabels := node.GetLabels()
+	r.Log.Info("Node Event", "Node", node.GetName(), "Node Labels", nodeLabels)
+
+	var cronSetObjs batchv1alpha1.CronSetList
+	_ = r.List(ctx, &cronSetObjs)
+
+	var requests []reconcile.Request
+
+	for _, cronSet := range cronSetObjs.Items {
+		r.Log.Info("Check CronSet for Node Event", "CronSet name", cronSet.Name, "selector", cronSet.Spec.Selector)
+
+		matched, err := nodeMatchesCronSet(&cronSet, node.(*corev1.Node))
+		if err != nil {
+			r.Log.Error(err, "Invalid node selection in CronSet", "CronSet", cronSet.Name)
+			continue
+		}
+		if !matched {
+			r.Log.Info("CronSet's node selector doesn't match event trigger node's label", "Node", node.GetName(), "CronSet", cronSet.Name)
+			continue
+		}
+
+		r.Log.Info("Add to request CronSet due to node event occured", "Node", node.GetName(), "CronSet", cronSet.Name)
+		nodeEventEnqueuesTotal.WithLabelValues(cronSet.Namespace, cronSet.Name).Inc()
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      cronSet.Name,
+				Namespace: cronSet.Namespace,
+			},
+		})
+	}
+
+	return requests
+}
+
 //+kubebuilder:rbac:groups=batch.grasse.io,resources=cronsets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch.grasse.io,resources=cronsets/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=batch.grasse.io,resources=cronsets/finalizers,verbs=update
 //+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -120,68 +167,271 @@ func (r *CronSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *CronSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	r.Log.Info("Reconcile:", "request name", req.Name, "request namespace", req.Namespace)
+	start := time.Now()
 
 	cronSet := &batchv1alpha1.CronSet{}
 	if err := r.Get(ctx, req.NamespacedName, cronSet); err != nil {
 		if errors.IsNotFound(err) {
 			r.Log.Info("CronSet not found", "cronset", cronSet.Name)
+			forgetCronSetMetrics(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		r.Log.Error(err, "Failed to get CronSet")
 		return ctrl.Result{}, err
 	}
+	defer observeReconcileDuration(req.NamespacedName, start)
 
-	var nodeSelector map[string]string
-	if cronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.NodeSelector != nil {
-		nodeSelector = cronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.NodeSelector
+	events := newEventAggregator(r.Recorder)
+	defer events.flush(cronSet)
+
+	if cronSet.DeletionTimestamp != nil {
+		if err := r.orphanCronJobs(ctx, cronSet, events); err != nil {
+			r.Log.Error(err, "Failed to orphan CronJobs", "cronset", cronSet.Name)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.releaseNodes(ctx, cronSet)
 	}
 
-	r.Log.Info("NodeSelector", "cronset", cronSet.Name, "nodeSelector", nodeSelector)
+	if err := r.syncDeletionPolicy(ctx, cronSet); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.adoptOrphanedCronJobs(ctx, cronSet, events); err != nil {
+		r.Log.Error(err, "Failed to adopt orphaned CronJobs", "cronset", cronSet.Name)
+		return ctrl.Result{}, err
+	}
+	if err := r.migrateLegacyCronJobs(ctx, cronSet); err != nil {
+		r.Log.Error(err, "Failed to migrate legacy CronJobs", "cronset", cronSet.Name)
+		return ctrl.Result{}, err
+	}
+
+	if cronSet.Spec.RollbackTo != nil {
+		// The update of the spec triggers a new reconcile.
+		return ctrl.Result{}, r.rollback(ctx, cronSet)
+	}
+
+	nodeSelector, err := nodeSelectorForCronSet(cronSet)
+	if err != nil {
+		r.Log.Error(err, "Invalid node selector", "cronset", cronSet.Name)
+		return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, cronSet, err)
+	}
+
+	r.Log.Info("NodeSelector", "cronset", cronSet.Name, "nodeSelector", nodeSelector.String())
 
 	nodeList := &corev1.NodeList{}
-	nodeMap := make(map[string]bool)
-	if err := r.List(ctx, nodeList, client.MatchingLabels(nodeSelector)); err != nil {
+	appliedCronJobs := make(map[string]bool)
+	if err := r.List(ctx, nodeList, client.MatchingLabelsSelector{Selector: nodeSelector}); err != nil {
 		r.Log.Error(err, "Failed to get node list")
 		return reconcile.Result{}, err
 	}
 
-	r.Log.Info("Matched", "node list", nodeList.Items)
+	selection, err := selectNodes(cronSet, nodeList.Items, time.Now())
+	if err != nil {
+		r.Log.Error(err, "Invalid node affinity", "cronset", cronSet.Name)
+		return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, cronSet, err)
+	}
+
+	r.Log.Info("Matched", "node list", selection.eligibleNodes)
+	for _, excludedNode := range selection.excludedNodes {
+		r.Log.Info("Exclude node", "cronset", cronSet.Name, "node", excludedNode.Name, "reason", excludedNode.Reason, "taint", excludedNode.Taint)
+	}
+
+	cronJobNames := assignCronJobNames(cronSet.Name, selection.eligibleNodes)
+	selectorToAdopt, err := adoptionSelector(cronSet)
+	if err != nil {
+		r.Log.Error(err, "Invalid adoption", "cronset", cronSet.Name)
+		return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, cronSet, err)
+	}
+	adoptions, err := r.findAdoptionCandidates(ctx, cronSet, selectorToAdopt, selection.eligibleNodes)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for nodeName, cronJob := range adoptions {
+		if cronJob.Name != cronJobNames[nodeName] {
+			// Replaced once the CronJob of the node is applied.
+			if err := r.suspendReplacedCronJob(ctx, cronSet, cronJob); err != nil {
+				return ctrl.Result{}, err
+			}
+			continue
+		}
+		if err := r.adoptCronJob(ctx, cronSet, cronJob, nodeName, events); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	existingCronJobs, err := r.listOwnedCronJobs(ctx, cronSet)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	existingCronJobsByName := make(map[string]*batchv1.CronJob, len(existingCronJobs))
+	for i := range existingCronJobs {
+		existingCronJobsByName[existingCronJobs[i].Name] = &existingCronJobs[i]
+	}
+	if _, err := renderSchedule(cronSet, cronSet.Name); err != nil {
+		r.Log.Error(err, "Invalid schedule", "cronset", cronSet.Name)
+		return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, cronSet, err)
+	}
+	if err := validateOverrides(cronSet); err != nil {
+		r.Log.Error(err, "Invalid overrides", "cronset", cronSet.Name)
+		return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, cronSet, err)
+	}
+	canaryNodes, err := selectCanaryNodes(cronSet, selection.eligibleNodes)
+	if err != nil {
+		r.Log.Error(err, "Invalid canary", "cronset", cronSet.Name)
+		return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, cronSet, err)
+	}
+	currentRevision, err := r.syncRevisions(ctx, cronSet, existingCronJobs)
+	if err != nil {
+		r.Log.Error(err, "Failed to sync ControllerRevisions", "cronset", cronSet.Name)
+		return ctrl.Result{}, err
+	}
+	jobsByCronJob, err := r.listJobsByCronJob(ctx, existingCronJobs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	canary, err := r.evaluateCanary(ctx, cronSet, canaryNodes, cronJobNames, existingCronJobsByName, jobsByCronJob, currentRevision, time.Now())
+	if err != nil {
+		r.Log.Error(err, "Failed to evaluate the canary", "cronset", cronSet.Name)
+		return ctrl.Result{}, err
+	}
+
+	templateCronSet := cronSet
+	rollout := &rolloutPlan{heldNodes: make(map[string]bool)}
+	if canary.failed() {
+		// Every node runs the previous template until the template changes again.
+		templateCronSet = canary.previousCronSet
+	} else {
+		rollout, err = planRollout(cronSet, selection.eligibleNodes, cronJobNames, existingCronJobsByName, jobsByCronJob,
+			selection.suspendedNodes, canary.progressingNodes())
+		if err != nil {
+			r.Log.Error(err, "Invalid update strategy", "cronset", cronSet.Name)
+			return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, cronSet, err)
+		}
+	}
+
+	// Held CronJobs are applied with the template of their revision, so that they still follow the
+	// nodes and the rest of the spec.
+	var revisionCronSets map[string]*batchv1alpha1.CronSet
+	if len(rollout.heldNodes) > 0 {
+		revisionCronSets, err = r.listRevisionCronSets(ctx, cronSet)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	nodeEvents := 0
 
 	misScheduledJobCount := 0
-	desiredScheduledJobCount := len(nodeList.Items)
-	for _, node := range nodeList.Items {
-		if err := r.applyCronJob(ctx, cronSet, &node); err != nil {
+	changedCronJobCount := 0
+	nodeFailures := make(map[string]nodeFailure)
+	desiredScheduledJobCount := len(selection.eligibleNodes)
+	for _, node := range selection.eligibleNodes {
+		cronJobName := cronJobNames[node.Name]
+		nodeCronSet := templateCronSet
+		if rollout.heldNodes[node.Name] {
+			r.Log.Info("Hold CronJob on its previous template", "cronset", cronSet.Name, "cronjob", cronJobName)
+			heldCronSet, ok := revisionCronSets[existingCronJobsByName[cronJobName].Labels[TemplateHashLabel]]
+			if !ok {
+				// The revision of the CronJob is unknown, e.g. it predates the revision history.
+				appliedCronJobs[cronJobName] = true
+				continue
+			}
+			nodeCronSet = heldCronSet
+		}
+		result, err := r.applyCronJob(ctx, nodeCronSet, &node, cronJobName, selection.suspendedNodes[node.Name])
+		if err != nil {
 			misScheduledJobCount++
+			nodeFailures[node.Name] = newNodeFailure(err)
+			applyFailuresTotal.WithLabelValues(cronSet.Namespace, cronSet.Name, node.Name, nodeFailures[node.Name].reason).Inc()
 			r.Log.Error(err, "Unable to apply cronjob resources.")
+			if errors.IsInvalid(err) {
+				events.add(corev1.EventTypeWarning, EventReasonDeletedInvalidCronJob, "Deleted invalid CronJob %s of node %s: %v", cronJobName, node.Name, err)
+			} else {
+				events.add(corev1.EventTypeWarning, EventReasonFailedApply, "Failed to apply CronJob %s on node %s: %v", cronJobName, node.Name, err)
+			}
+			if nodeEvents < maxEventsPerReason {
+				r.Recorder.Eventf(&node, corev1.EventTypeWarning, EventReasonFailedApply, "Failed to apply CronJob %s/%s of CronSet %s: %v", cronSet.Namespace, cronJobName, cronSet.Name, err)
+				nodeEvents++
+			}
 			continue
 		}
-		nodeMap[node.Name] = true
+		switch result {
+		case controllerutil.OperationResultCreated:
+			events.add(corev1.EventTypeNormal, EventReasonSuccessfulCreate, "Created CronJob %s on node %s", cronJobName, node.Name)
+		case controllerutil.OperationResultUpdated:
+			events.add(corev1.EventTypeNormal, EventReasonSuccessfulUpdate, "Updated CronJob %s on node %s", cronJobName, node.Name)
+		}
+		if result != controllerutil.OperationResultNone {
+			changedCronJobCount++
+		}
+		appliedCronJobs[cronJobNames[node.Name]] = true
+	}
+
+	scheduledCronJobCount := 0
+	for _, node := range selection.eligibleNodes {
+		if appliedCronJobs[cronJobNames[node.Name]] {
+			scheduledCronJobCount++
+		}
+	}
+
+	for nodeName, cronJob := range adoptions {
+		cronJobName := cronJobNames[nodeName]
+		if _, failed := nodeFailures[nodeName]; failed || cronJob.Name == cronJobName || !appliedCronJobs[cronJobName] {
+			continue
+		}
+		if err := r.replaceCronJob(ctx, cronSet, cronJob, cronJobName, nodeName, events); err != nil {
+			r.Log.Error(err, "Failed to replace adopted CronJob", "cronset", cronSet.Name, "cronjob", cronJob.Name)
+			return ctrl.Result{}, err
+		}
 	}
 
-	err := r.cleanUpCronJob(ctx, cronSet.Name, nodeMap)
+	deletedCronJobs, gracePeriodRequeueAfter, err := r.cleanUpCronJob(ctx, cronSet, appliedCronJobs, cronJobNames, events, time.Now())
+	for _, cronJob := range deletedCronJobs {
+		events.add(corev1.EventTypeNormal, EventReasonSuccessfulDelete, "Deleted CronJob %s of node %s", cronJob.Name, cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	changedCronJobCount += len(deletedCronJobs)
+
+	ownedCronJobs, err := r.listOwnedCronJobs(ctx, cronSet)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	currentDependentCronJobCount, err := r.getDependentCronJobCount(ctx, cronSet.Name)
+	protectionRequeueAfter, err := r.syncNodeProtection(ctx, cronSet, ownedCronJobs, events, time.Now())
 	if err != nil {
+		r.Log.Error(err, "Failed to sync the node protection", "cronset", cronSet.Name)
 		return ctrl.Result{}, err
 	}
 
-	if err := r.updateStatus(cronSet, CronSetStatus{
-		CurrentDependentCronJobCount: currentDependentCronJobCount,
+	status := CronSetStatus{
+		CurrentDependentCronJobCount: int32(len(ownedCronJobs)),
 		MisScheduledJobCount:         int32(misScheduledJobCount),
 		DesiredScheduledJobCount:     int32(desiredScheduledJobCount),
-	}); err != nil {
+		ScheduledCronJobCount:        int32(scheduledCronJobCount),
+		ExcludedNodes:                selection.excludedNodes,
+		NodeFailures:                 nodeFailures,
+		NodeStatuses:                 buildNodeStatuses(ownedCronJobs, cronJobNames, nodeFailures),
+		ChangedCronJobCount:          int32(changedCronJobCount),
+		UpdatedCronJobCount:          rollout.updatedCount,
+		UnavailableCronJobCount:      rollout.unavailableCount,
+		RolloutInProgress:            rollout.inProgress,
+		CurrentRevision:              currentRevision,
+	}
+	if canary != nil {
+		status.Canary = canary.status
+		status.CanaryNodesMissing = canary.nodesMissing()
+	}
+	recordCronSetMetrics(req.NamespacedName, status, ownedCronJobs)
+	if err := r.updateStatus(cronSet, status); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	requeueAfter := earliestRequeue(selection.requeueAfter, protectionRequeueAfter)
+	requeueAfter = earliestRequeue(requeueAfter, gracePeriodRequeueAfter)
+	return ctrl.Result{RequeueAfter: earliestRequeue(requeueAfter, canary.requeue())}, nil
 }
 
-func (r *CronSetReconciler) applyCronJob(ctx context.Context, cronSet *batchv1alpha1.CronSet, node *corev1.Node) error {
-	nodeIdentifier := getNodeIdentifier(node)
-	cronJobName := generateCronJobName(cronSet.Name, nodeIdentifier)
+func (r *CronSetReconciler) applyCronJob(ctx context.Context, cronSet *batchv1alpha1.CronSet, node *corev1.Node, cronJobName string, suspended bool) (controllerutil.OperationResult, error) {
 	cronJobKey := metav1.ObjectMeta{
 		Name:      cronJobName,
 		Namespace: cronSet.Namespace,
@@ -190,51 +440,96 @@ func (r *CronSetReconciler) applyCronJob(ctx context.Context, cronSet *batchv1al
 		ObjectMeta: cronJobKey,
 	}
 
-	_, err := ctrl.CreateOrUpdate(ctx, r.Client, cronJob, func() error {
-		updateCronJobSpec(cronJob, cronSet, node.Name)
+	result, err := ctrl.CreateOrUpdate(ctx, r.Client, cronJob, func() error {
+		previousTemplateHash := cronJob.Labels[TemplateHashLabel]
+		templateUpdatedAt := cronJob.Annotations[TemplateUpdatedAnnotation]
+		adoptedFrom := cronJob.Annotations[AdoptedFromAnnotation]
+		if err := updateCronJobSpec(cronJob, cronSet, node, suspended); err != nil {
+			return err
+		}
+		if cronJob.ResourceVersion != "" && previousTemplateHash != cronJob.Labels[TemplateHashLabel] {
+			templateUpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		}
+		if templateUpdatedAt != "" {
+			cronJob.Annotations[TemplateUpdatedAnnotation] = templateUpdatedAt
+		}
+		if adoptedFrom != "" {
+			cronJob.Annotations[AdoptedFromAnnotation] = adoptedFrom
+		}
 		return controllerutil.SetControllerReference(cronSet, cronJob, r.Scheme)
 	})
-	if err != nil && errors.IsInvalid(err) {
-		_ = r.Delete(ctx, &batchv1.CronJob{ObjectMeta: cronJobKey}, client.PropagationPolicy("Background"))
-		return err
+	if err != nil {
+		if errors.IsInvalid(err) {
+			_ = r.Delete(ctx, &batchv1.CronJob{ObjectMeta: cronJobKey}, client.PropagationPolicy("Background"))
+		}
+		return result, err
 	}
-	r.Log.Info("Create or Update CronJob", "cronset", cronSet.Name, "cronjob", cronJob.Name)
+	r.Log.Info("Create or Update CronJob", "cronset", cronSet.Name, "cronjob", cronJob.Name, "result", result)
 
-	return nil
+	return result, nil
 }
 
-func (r *CronSetReconciler) cleanUpCronJob(ctx context.Context, cronSetName string, nodeMap map[string]bool) error {
-	cronJobList := &batchv1.CronJobList{}
-	cronSetSelector := map[string]string{OwnerLabel: cronSetName}
-	if err := r.List(ctx, cronJobList, client.MatchingLabels(cronSetSelector)); err != nil && !errors.IsNotFound(err) {
-		return err
+// cleanUpCronJob deletes the CronJobs of the CronSet that were not applied in this reconcile, i.e. the
+// CronJobs of departed nodes and the ones left behind by a change of the CronJob name of a node.
+// The CronJob of a departed node is kept suspended during the node removal grace period, then only
+// deleted once its node removal hook finished, if any.
+// It returns the deleted CronJobs, and when the next grace period ends.
+func (r *CronSetReconciler) cleanUpCronJob(ctx context.Context, cronSet *batchv1alpha1.CronSet, appliedCronJobs map[string]bool,
+	cronJobNames map[string]string, events *eventAggregator, now time.Time) ([]batchv1.CronJob, time.Duration, error) {
+	cronJobs, err := r.listOwnedCronJobs(ctx, cronSet)
+	if err != nil {
+		return nil, 0, err
 	}
-	for _, cronJob := range cronJobList.Items {
-		if _, exist := nodeMap[cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName]; !exist {
-			if err := r.Delete(ctx, &cronJob, &client.DeleteOptions{}); err != nil {
-				return err
+	var deleted []batchv1.CronJob
+	var requeueAfter time.Duration
+	for _, cronJob := range cronJobs {
+		if appliedCronJobs[cronJob.Name] {
+			continue
+		}
+		nodeName := cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName
+		// A CronJob renamed on a node which is still eligible is deleted right away.
+		if _, eligible := cronJobNames[nodeName]; !eligible {
+			if cronSet.Spec.NodeRemovalGracePeriod != nil {
+				remaining, err := r.deferCronJobDeletion(ctx, cronSet, &cronJob, events, now)
+				if err != nil {
+					return deleted, requeueAfter, err
+				}
+				if remaining > 0 {
+					requeueAfter = earliestRequeue(requeueAfter, remaining)
+					continue
+				}
+			}
+			if cronSet.Spec.OnNodeRemoval != nil {
+				done, err := r.runNodeRemovalHook(ctx, cronSet, &cronJob, events)
+				if err != nil {
+					return deleted, requeueAfter, err
+				}
+				if !done {
+					continue
+				}
 			}
-			r.Log.Info("CleanUp CronJob", "cronjob", cronJob.Name, "node", cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName)
 		}
+		if err := r.Delete(ctx, &cronJob, client.Preconditions{UID: &cronJob.UID}); err != nil && !errors.IsNotFound(err) {
+			return deleted, requeueAfter, err
+		}
+		deleted = append(deleted, cronJob)
+		r.Log.Info("CleanUp CronJob", "cronjob", cronJob.Name, "node", nodeName)
 	}
-	return nil
-}
-
-func (r *CronSetReconciler) getDependentCronJobCount(ctx context.Context, cronSetName string) (int32, error) {
-	var dependentCronJobList batchv1.CronJobList
-	selector := labels.SelectorFromSet(map[string]string{
-		OwnerLabel: cronSetName,
-	})
-	if err := r.List(ctx, &dependentCronJobList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
-		return 0, err
-	}
-	return int32(len(dependentCronJobList.Items)), nil
+	return deleted, requeueAfter, r.deleteNodeRemovalHookJobs(ctx, cronSet)
 }
 
 func (r *CronSetReconciler) updateStatus(cronset *batchv1alpha1.CronSet, status CronSetStatus) error {
 	cronset.Status.CurrentNumberScheduled = status.CurrentDependentCronJobCount
 	cronset.Status.NumberMisscheduled = status.MisScheduledJobCount
 	cronset.Status.DesiredNumberScheduled = status.DesiredScheduledJobCount
+	cronset.Status.ExcludedNodes = status.ExcludedNodes
+	cronset.Status.ObservedGeneration = cronset.Generation
+	cronset.Status.Nodes = status.NodeStatuses
+	cronset.Status.UpdatedNumberScheduled = status.UpdatedCronJobCount
+	cronset.Status.NumberUnavailable = status.UnavailableCronJobCount
+	cronset.Status.CurrentRevision = status.CurrentRevision
+	cronset.Status.Canary = status.Canary
+	setStatusConditions(cronset, status)
 
 	if err := r.Status().Update(context.TODO(), cronset); err != nil {
 		return err
@@ -242,28 +537,73 @@ func (r *CronSetReconciler) updateStatus(cronset *batchv1alpha1.CronSet, status
 	return nil
 }
 
-func getNodeIdentifier(node *corev1.Node) string {
-	identificationKey := os.Getenv(NodeIdentificationKey)
-	if annotationValue, ok := node.Annotations[identificationKey]; ok {
-		return annotationValue
+// updateCronJobSpec generates the CronJob of the node from the CronSet, patched by the first
+// matching template override. It fails when the override or the node attributes can't be applied.
+func updateCronJobSpec(cronJob *batchv1.CronJob, cronSet *batchv1alpha1.CronSet, node *corev1.Node, suspended bool) error {
+	nodeName := node.Name
+	templateHash := computeTemplateHash(cronSet)
+	override := findOverride(cronSet, node)
+	if override != nil {
+		template, err := applyOverride(cronSet, override)
+		if err != nil {
+			return err
+		}
+		cronSet = cronSet.DeepCopy()
+		cronSet.Spec.CronJobTemplate = *template
 	}
-	return node.Name
-}
-
-func generateCronJobName(cronSetName string, nodeIdentifier string) string {
-	return strings.Join([]string{cronSetName, nodeIdentifier}, "-")
-}
 
-func updateCronJobSpec(cronJob *batchv1.CronJob, cronSet *batchv1alpha1.CronSet, nodeName string) {
-	cronJobSpec := cronSet.Spec.CronJobTemplate.Spec
+	cronJobSpec := *cronSet.Spec.CronJobTemplate.Spec.DeepCopy()
+	schedule, err := renderSchedule(cronSet, getNodeIdentifier(node))
+	if err != nil {
+		return err
+	}
+	cronJobSpec.Schedule = schedule
+	if err := renderNodeTemplates(&cronJobSpec.JobTemplate.Spec.Template.Spec, node); err != nil {
+		return err
+	}
 	cronJobSpec.JobTemplate.Spec.Template.Spec.NodeName = nodeName
-
-	cronJobLabels := cronSet.Labels
-	if cronJobLabels == nil {
-		cronJobLabels = make(map[string]string)
+	cronJobSpec.JobTemplate.Spec.Template.Spec.Tolerations = podTolerations(cronSet)
+	if suspended {
+		cronJobSpec.Suspend = ptr.To(true)
+	}
+	if cronSet.Spec.TerminationProtection != nil {
+		if cronJobSpec.JobTemplate.Spec.Template.Labels == nil {
+			cronJobSpec.JobTemplate.Spec.Template.Labels = make(map[string]string)
+		}
+		maps.Copy(cronJobSpec.JobTemplate.Spec.Template.Labels, protectedPodLabels(cronSet, nodeName))
+	}
+	if scaleDownProtectionPolicy(cronSet) == batchv1alpha1.ScaleDownProtectionPod {
+		if cronJobSpec.JobTemplate.Spec.Template.Annotations == nil {
+			cronJobSpec.JobTemplate.Spec.Template.Annotations = make(map[string]string)
+		}
+		maps.Copy(cronJobSpec.JobTemplate.Spec.Template.Annotations, scaleDownDisabledPodAnnotations)
 	}
-	cronJobLabels[OwnerLabel] = cronSet.Name
 
-	cronJob.ObjectMeta.Labels = cronJobLabels
+	cronJob.ObjectMeta.Labels = cronJobLabels(cronSet)
+	cronJob.ObjectMeta.Labels[NodeLabel] = truncateLabelValue(nodeName)
+	cronJob.ObjectMeta.Labels[TemplateHashLabel] = templateHash
+	cronJob.ObjectMeta.Annotations = maps.Clone(cronSet.Spec.CronJobTemplate.Annotations)
+	if cronJob.ObjectMeta.Annotations == nil {
+		cronJob.ObjectMeta.Annotations = make(map[string]string)
+	}
+	cronJob.ObjectMeta.Annotations[NodeNameAnnotation] = nodeName
+	if override != nil {
+		cronJob.ObjectMeta.Annotations[OverrideAnnotation] = override.Name
+	}
 	cronJob.Spec = cronJobSpec
+	return nil
+}
+
+// cronJobLabels returns the labels of a CronJob generated from the CronSet: the labels of the CronSet
+// according to its label propagation policy, overridden by the labels of the CronJob template,
+// and the owner labels on top. The CronSet is never mutated.
+func cronJobLabels(cronSet *batchv1alpha1.CronSet) map[string]string {
+	cronJobLabels := make(map[string]string)
+	if cronSet.Spec.LabelPropagationPolicy != batchv1alpha1.LabelPropagationNone {
+		maps.Copy(cronJobLabels, cronSet.Labels)
+	}
+	maps.Copy(cronJobLabels, cronSet.Spec.CronJobTemplate.Labels)
+	cronJobLabels[OwnerLabel] = truncateLabelValue(cronSet.Name)
+	cronJobLabels[OwnerUIDLabel] = string(cronSet.UID)
+	return cronJobLabels
 }
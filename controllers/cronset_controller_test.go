@@ -2,28 +2,45 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/suite"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
 	CronSetName      = "test-cronset"
 	CronSetNamespace = "default"
+	CronSetUID       = "6f1e2d3c-0000-4000-8000-000000000001"
 )
 
 var trueVal = true
@@ -34,7 +51,7 @@ var cronSetKey = types.NamespacedName{
 }
 
 var expectedOwnerRefs = []metav1.OwnerReference{{
-	APIVersion: "batch.grasse.io/v1alpha1", Kind: "CronSet", Name: CronSetName,
+	APIVersion: "batch.grasse.io/v1alpha1", Kind: "CronSet", Name: CronSetName, UID: CronSetUID,
 	Controller: &trueVal, BlockOwnerDeletion: &trueVal,
 }}
 
@@ -42,6 +59,7 @@ type CronSetSuite struct {
 	suite.Suite
 	reconciler CronSetReconciler
 	fakeClient client.Client
+	recorder   *record.FakeRecorder
 	node       *corev1.Node
 	cronSet    *batchv1alpha1.CronSet
 }
@@ -52,6 +70,8 @@ func (s *CronSetSuite) SetupTest() {
 	require.NoError(s.T(), err)
 	require.NoError(s.T(), corev1.SchemeBuilder.AddToScheme(scheme))
 	require.NoError(s.T(), batchv1.SchemeBuilder.AddToScheme(scheme))
+	require.NoError(s.T(), appsv1.SchemeBuilder.AddToScheme(scheme))
+	require.NoError(s.T(), policyv1.SchemeBuilder.AddToScheme(scheme))
 
 	s.node = &corev1.Node{
 		ObjectMeta: metav1.ObjectMeta{
@@ -69,6 +89,7 @@ func (s *CronSetSuite) SetupTest() {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      CronSetName,
 			Namespace: CronSetNamespace,
+			UID:       CronSetUID,
 		},
 		Spec: batchv1alpha1.CronSetSpec{
 			CronJobTemplate: batchv1alpha1.CronJobTemplateSpec{
@@ -95,12 +116,15 @@ func (s *CronSetSuite) SetupTest() {
 		},
 	}
 
-	s.fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(s.node).WithObjects(s.cronSet).WithStatusSubresource(s.cronSet).Build()
+	s.fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(s.node).WithObjects(s.cronSet).WithStatusSubresource(s.cronSet).
+		WithIndex(&batchv1.Job{}, jobControllerUIDField, indexJobByControllerUID).Build()
 
+	s.recorder = record.NewFakeRecorder(1000)
 	s.reconciler = CronSetReconciler{
-		s.fakeClient,
-		ctrl.Log.WithName("controllers").WithName("CronSet"),
-		scheme,
+		Client:   s.fakeClient,
+		Log:      ctrl.Log.WithName("controllers").WithName("CronSet"),
+		Scheme:   scheme,
+		Recorder: s.recorder,
 	}
 }
 
@@ -320,3 +344,2504 @@ func (s *CronSetSuite) TestNodeEvent_UpdateLabel_RemoveCronJob() {
 		})
 	})
 }
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateSelector_SelectNodesByExpressions() {
+	gpuNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "gpu-node",
+			Labels: map[string]string{"foo": "bar", "pool": "gpu"},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, gpuNode))
+
+	nodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, s.node.Name),
+		Namespace: CronSetNamespace,
+	}
+	gpuNodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, gpuNode.Name),
+		Namespace: CronSetNamespace,
+	}
+
+	s.Run("When updating a CronSet selector to exclude a node pool", func() {
+		createdCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.Selector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "pool", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"gpu"}},
+			},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should create a CronJob only into the nodes outside of the pool", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.NoError(s.T(), err)
+			err = s.fakeClient.Get(ctx, gpuNodeCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+		})
+
+		s.Run("Should enqueue the CronSet only for node events of the matched nodes", func() {
+			assert.Len(s.T(), s.reconciler.findCronSetsForNode(ctx, s.node), 1)
+			assert.Empty(s.T(), s.reconciler.findCronSetsForNode(ctx, gpuNode))
+		})
+	})
+
+	s.Run("When updating a CronSet selector to require a label to exist", func() {
+		createdCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.Selector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "pool", Operator: metav1.LabelSelectorOpExists},
+			},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should move the CronJob to the nodes having the label", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+			err = s.fakeClient.Get(ctx, gpuNodeCronJobKey, &batchv1.CronJob{})
+			assert.NoError(s.T(), err)
+		})
+	})
+
+	s.Run("When updating a CronSet selector with an invalid operator", func() {
+		createdCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.Selector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "pool", Operator: "Unknown"},
+			},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+
+		s.Run("Should return a terminal error", func() {
+			assert.Error(s.T(), err)
+			assert.ErrorIs(s.T(), err, reconcile.TerminalError(nil))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateNodeAffinity_SelectNodesByAffinity() {
+	otherNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "other-node",
+			Labels: map[string]string{"foo": "bar", "zone": "a"},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, otherNode))
+
+	nodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, s.node.Name),
+		Namespace: CronSetNamespace,
+	}
+	otherNodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, otherNode.Name),
+		Namespace: CronSetNamespace,
+	}
+
+	updateAffinity := func(terms ...corev1.NodeSelectorTerm) {
+		createdCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.Affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+			},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+	}
+
+	s.Run("When updating a CronSet with a required node affinity on labels", func() {
+		updateAffinity(corev1.NodeSelectorTerm{
+			MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+			},
+		})
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should create a CronJob only into the nodes satisfying the affinity", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+			err = s.fakeClient.Get(ctx, otherNodeCronJobKey, &batchv1.CronJob{})
+			assert.NoError(s.T(), err)
+		})
+
+		s.Run("Should count only the eligible nodes as desired", func() {
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.DesiredNumberScheduled)
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.CurrentNumberScheduled)
+		})
+
+		s.Run("Should enqueue the CronSet only for node events of the eligible nodes", func() {
+			assert.Empty(s.T(), s.reconciler.findCronSetsForNode(ctx, s.node))
+			assert.Len(s.T(), s.reconciler.findCronSetsForNode(ctx, otherNode), 1)
+		})
+	})
+
+	s.Run("When updating a CronSet with a required node affinity on the node name field", func() {
+		updateAffinity(corev1.NodeSelectorTerm{
+			MatchFields: []corev1.NodeSelectorRequirement{
+				{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{s.node.Name}},
+			},
+		})
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should create a CronJob only into the named node", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.NoError(s.T(), err)
+			err = s.fakeClient.Get(ctx, otherNodeCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestNodeEvent_UpdateTaint_ExcludeNode() {
+	nodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, s.node.Name),
+		Namespace: CronSetNamespace,
+	}
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	assert.NoError(s.T(), err)
+
+	taint := corev1.Taint{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}
+
+	s.Run("When tainting a node with a taint that is not tolerated by the CronSet", func() {
+		createdNode := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, createdNode))
+		createdNode.Spec.Taints = []corev1.Taint{taint}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdNode))
+
+		s.Run("Should enqueue the CronSet to re-evaluate the node", func() {
+			assert.Len(s.T(), s.reconciler.findCronSetsForNode(ctx, createdNode), 1)
+		})
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should delete the CronJob object and report the offending taint", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(0), updatedCronSet.Status.DesiredNumberScheduled)
+			require.Len(s.T(), updatedCronSet.Status.ExcludedNodes, 1)
+			assert.Equal(s.T(), s.node.Name, updatedCronSet.Status.ExcludedNodes[0].Name)
+			assert.Equal(s.T(), batchv1alpha1.ExcludedNodeReasonUntoleratedTaint, updatedCronSet.Status.ExcludedNodes[0].Reason)
+			assert.Equal(s.T(), taint, *updatedCronSet.Status.ExcludedNodes[0].Taint)
+		})
+	})
+
+	s.Run("When adding a toleration for the taint to the CronSet", func() {
+		createdCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.Tolerations = []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should create the CronJob object again", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.NoError(s.T(), err)
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Empty(s.T(), updatedCronSet.Status.ExcludedNodes)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_EnableDefaultTolerations_InjectTolerations() {
+	nodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, s.node.Name),
+		Namespace: CronSetNamespace,
+	}
+	userToleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+
+	createdNode := &corev1.Node{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, createdNode))
+	createdNode.Spec.Unschedulable = true
+	createdNode.Spec.Taints = []corev1.Taint{
+		{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoExecute},
+	}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, createdNode))
+
+	s.Run("When reconcile a CronSet without default tolerations on a not-ready cordoned node", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should not create a CronJob object", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+		})
+	})
+
+	s.Run("When enabling the DaemonSet default tolerations", func() {
+		createdCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.DefaultTolerations = batchv1alpha1.DefaultTolerationsDaemonSet
+		createdCronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.Tolerations = []corev1.Toleration{userToleration}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should create a CronJob object with the default tolerations", func() {
+			createdCronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, nodeCronJobKey, createdCronJob))
+			tolerations := createdCronJob.Spec.JobTemplate.Spec.Template.Spec.Tolerations
+			assert.Equal(s.T(), append([]corev1.Toleration{userToleration}, daemonSetTolerations...), tolerations)
+		})
+
+		s.Run("Should not mutate the pod template of the CronSet", func() {
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), []corev1.Toleration{userToleration}, updatedCronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.Tolerations)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestNodeEvent_Unhealthy_ApplyNodeHealthPolicy() {
+	nodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, s.node.Name),
+		Namespace: CronSetNamespace,
+	}
+
+	updatePolicy := func(policyType batchv1alpha1.NodeHealthPolicyType) {
+		createdCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.NodeHealthPolicy = &batchv1alpha1.NodeHealthPolicy{
+			Type:                policyType,
+			NotReadyGracePeriod: &metav1.Duration{Duration: time.Minute},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+	}
+	updateNode := func(mutate func(node *corev1.Node)) {
+		createdNode := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, createdNode))
+		mutate(createdNode)
+		status := createdNode.Status.DeepCopy()
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdNode))
+		createdNode.Status = *status
+		require.NoError(s.T(), s.fakeClient.Status().Update(ctx, createdNode))
+	}
+	notReadySince := func(since time.Time) func(node *corev1.Node) {
+		return func(node *corev1.Node) {
+			node.Status.Conditions = []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse, LastTransitionTime: metav1.NewTime(since)},
+			}
+			node.Spec.Taints = []corev1.Taint{{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoExecute}}
+		}
+	}
+
+	s.Run("When cordoning a node with the Suspend policy", func() {
+		updatePolicy(batchv1alpha1.NodeHealthPolicySuspend)
+		updateNode(func(node *corev1.Node) {
+			node.Spec.Unschedulable = true
+			node.Spec.Taints = []corev1.Taint{{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}}
+		})
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should suspend the CronJob object", func() {
+			createdCronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, nodeCronJobKey, createdCronJob))
+			assert.Equal(s.T(), ptr.To(true), createdCronJob.Spec.Suspend)
+		})
+	})
+
+	s.Run("When uncordoning the node", func() {
+		updateNode(func(node *corev1.Node) {
+			node.Spec.Unschedulable = false
+			node.Spec.Taints = nil
+		})
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should resume the CronJob object", func() {
+			createdCronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, nodeCronJobKey, createdCronJob))
+			assert.Nil(s.T(), createdCronJob.Spec.Suspend)
+		})
+	})
+
+	s.Run("When a node has just become NotReady with the Remove policy", func() {
+		updatePolicy(batchv1alpha1.NodeHealthPolicyRemove)
+		updateNode(notReadySince(time.Now()))
+
+		result, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should keep the CronJob object during the grace period", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.NoError(s.T(), err)
+			assert.Greater(s.T(), result.RequeueAfter, time.Duration(0))
+			assert.LessOrEqual(s.T(), result.RequeueAfter, time.Minute)
+		})
+	})
+
+	s.Run("When a node has been NotReady longer than the grace period with the Remove policy", func() {
+		updateNode(notReadySince(time.Now().Add(-2 * time.Minute)))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should delete the CronJob object and report the node", func() {
+			err := s.fakeClient.Get(ctx, nodeCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			require.Len(s.T(), updatedCronSet.Status.ExcludedNodes, 1)
+			assert.Equal(s.T(), batchv1alpha1.ExcludedNodeReasonNodeNotReady, updatedCronSet.Status.ExcludedNodes[0].Reason)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplateMetadata_PropagateMetadata() {
+	nodeCronJobKey := types.NamespacedName{
+		Name:      generateCronJobName(CronSetName, s.node.Name),
+		Namespace: CronSetNamespace,
+	}
+
+	createdCronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+	createdCronSet.Labels = map[string]string{"team": "infra", "cost-center": "cronset"}
+	createdCronSet.Spec.CronJobTemplate.Labels = map[string]string{"cost-center": "template", "app": "log-rotate"}
+	createdCronSet.Spec.CronJobTemplate.Annotations = map[string]string{"monitoring/enabled": "true"}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+	s.Run("When reconcile a CronSet with template metadata", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should apply the CronSet labels, the template metadata and the owner label", func() {
+			createdCronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, nodeCronJobKey, createdCronJob))
+			assert.Equal(s.T(), map[string]string{
+				"team":            "infra",
+				"cost-center":     "template",
+				"app":             "log-rotate",
+				OwnerLabel:        CronSetName,
+				OwnerUIDLabel:     CronSetUID,
+				NodeLabel:         s.node.Name,
+				TemplateHashLabel: computeTemplateHash(createdCronSet),
+			}, createdCronJob.Labels)
+			assert.Equal(s.T(), map[string]string{"monitoring/enabled": "true", NodeNameAnnotation: s.node.Name}, createdCronJob.Annotations)
+		})
+	})
+
+	s.Run("When disabling the propagation of the CronSet labels", func() {
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, createdCronSet))
+		createdCronSet.Spec.LabelPropagationPolicy = batchv1alpha1.LabelPropagationNone
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should apply only the template labels and the owner label", func() {
+			createdCronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, nodeCronJobKey, createdCronJob))
+			assert.Equal(s.T(), map[string]string{
+				"cost-center":     "template",
+				"app":             "log-rotate",
+				OwnerLabel:        CronSetName,
+				OwnerUIDLabel:     CronSetUID,
+				NodeLabel:         s.node.Name,
+				TemplateHashLabel: computeTemplateHash(createdCronSet),
+			}, createdCronJob.Labels)
+		})
+	})
+
+	s.Run("When generating a CronJob spec", func() {
+		cronSet := createdCronSet.DeepCopy()
+		cronSet.Spec.LabelPropagationPolicy = batchv1alpha1.LabelPropagationAll
+		require.NoError(s.T(), updateCronJobSpec(&batchv1.CronJob{}, cronSet, s.node, false))
+
+		s.Run("Should not mutate the labels of the CronSet", func() {
+			assert.Equal(s.T(), map[string]string{"team": "infra", "cost-center": "cronset"}, cronSet.Labels)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_SameNameInOtherNamespace_KeepCronJobs() {
+	otherCronSet := s.cronSet.DeepCopy()
+	otherCronSet.Namespace = "other"
+	otherCronSet.UID = "6f1e2d3c-0000-4000-8000-000000000002"
+	otherCronSet.ResourceVersion = ""
+	require.NoError(s.T(), s.fakeClient.Create(ctx, otherCronSet))
+	otherCronSetKey := types.NamespacedName{Name: CronSetName, Namespace: otherCronSet.Namespace}
+
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	assert.NoError(s.T(), err)
+	_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: otherCronSetKey})
+	assert.NoError(s.T(), err)
+
+	s.Run("When one of the CronSets with the same name no longer selects the node", func() {
+		require.NoError(s.T(), s.fakeClient.Get(ctx, otherCronSetKey, otherCronSet))
+		otherCronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.NodeSelector = map[string]string{"foo": "bar1"}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, otherCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: otherCronSetKey})
+		assert.NoError(s.T(), err)
+		_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should delete only its own CronJob and count only its own CronJobs", func() {
+			err := s.fakeClient.Get(ctx, types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: otherCronSet.Namespace}, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+			err = s.fakeClient.Get(ctx, types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}, &batchv1.CronJob{})
+			assert.NoError(s.T(), err)
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.CurrentNumberScheduled)
+			require.NoError(s.T(), s.fakeClient.Get(ctx, otherCronSetKey, otherCronSet))
+			assert.Equal(s.T(), int32(0), otherCronSet.Status.CurrentNumberScheduled)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Upgrade_MigrateLegacyCronJobs() {
+	legacyCronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            generateCronJobName(CronSetName, "departed-node"),
+			Namespace:       CronSetNamespace,
+			Labels:          map[string]string{OwnerLabel: CronSetName},
+			OwnerReferences: expectedOwnerRefs,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "1 * * * *",
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{NodeName: "departed-node"}},
+				},
+			},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, legacyCronJob))
+	legacyCronJobKey := types.NamespacedName{Name: legacyCronJob.Name, Namespace: CronSetNamespace}
+
+	s.Run("When reconcile a CronSet owning a CronJob created by an older version", func() {
+		require.NoError(s.T(), s.reconciler.migrateLegacyCronJobs(ctx, s.cronSet))
+
+		s.Run("Should add the owner UID label to the CronJob", func() {
+			migratedCronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, legacyCronJobKey, migratedCronJob))
+			assert.Equal(s.T(), CronSetUID, migratedCronJob.Labels[OwnerUIDLabel])
+		})
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should clean up the migrated CronJob of the departed node", func() {
+			err := s.fakeClient.Get(ctx, legacyCronJobKey, &batchv1.CronJob{})
+			assert.Equal(s.T(), true, errors.IsNotFound(err))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestTruncateLabelValue_LongValue_FitIntoLabelValue() {
+	longName := strings.Repeat("log-rotate-", 10)
+	value := truncateLabelValue(longName)
+
+	assert.Len(s.T(), value, 63)
+	assert.Empty(s.T(), validation.IsValidLabelValue(value))
+	assert.NotEqual(s.T(), value, truncateLabelValue(longName+"x"))
+	assert.Equal(s.T(), CronSetName, truncateLabelValue(CronSetName))
+}
+
+func (s *CronSetSuite) TestGenerateCronJobName_LongNodeName_FitIntoCronJobName() {
+	cronSetName := "node-log-rotation-and-cleanup"
+	nodeNames := []string{
+		"ip-10-0-123-45.eu-west-1.compute.internal",
+		"ip-10-0-123-46.eu-west-1.compute.internal",
+	}
+
+	names := map[string]bool{}
+	for _, nodeName := range nodeNames {
+		name := generateCronJobName(cronSetName, nodeName)
+		assert.Len(s.T(), name, cronJobMaxNameLength)
+		assert.Empty(s.T(), validation.IsDNS1123Subdomain(name))
+		assert.Equal(s.T(), name, generateCronJobName(cronSetName, nodeName))
+		names[name] = true
+	}
+	assert.Len(s.T(), names, len(nodeNames))
+	assert.Equal(s.T(), CronSetName+"-test-node", generateCronJobName(CronSetName, "test-node"))
+
+	s.Run("Should generate valid names when the cut or the identifier ends with a special character", func() {
+		name := generateCronJobName("node-log-rotation-and-clean", nodeNames[0])
+		assert.LessOrEqual(s.T(), len(name), cronJobMaxNameLength)
+		assert.Empty(s.T(), validation.IsDNS1123Subdomain(name))
+		assert.NotContains(s.T(), name, ".-")
+
+		for _, nodeIdentifier := range []string{"Node_", "_node", "node_.example", "..", "___"} {
+			name := generateCronJobName("job", nodeIdentifier)
+			assert.Empty(s.T(), validation.IsDNS1123Subdomain(name), nodeIdentifier)
+		}
+		assert.Equal(s.T(), "job-node", generateCronJobName("job", "Node_"))
+		assert.Equal(s.T(), "job-node.example", generateCronJobName("job", "node_.example"))
+	})
+}
+
+func (s *CronSetSuite) TestAssignCronJobNames_SharedIdentifier_KeepNamesStable() {
+	os.Setenv(NodeIdentificationKey, "xyz")
+	defer os.Unsetenv(NodeIdentificationKey)
+	newNode := func(name string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{"xyz": "shared"}}}
+	}
+
+	names := assignCronJobNames(CronSetName, []corev1.Node{newNode("node-b"), newNode("node-c")})
+	assert.NotEqual(s.T(), names["node-b"], names["node-c"])
+	assert.NotEqual(s.T(), CronSetName+"-shared", names["node-b"])
+	assert.NotEqual(s.T(), CronSetName+"-shared", names["node-c"])
+
+	s.Run("Should keep the name of a node when another node with the same identifier comes or goes", func() {
+		withNewNode := assignCronJobNames(CronSetName, []corev1.Node{newNode("node-a"), newNode("node-b"), newNode("node-c")})
+		assert.Equal(s.T(), names["node-b"], withNewNode["node-b"])
+		assert.Equal(s.T(), names["node-c"], withNewNode["node-c"])
+
+		withoutNode := assignCronJobNames(CronSetName, []corev1.Node{newNode("node-a"), newNode("node-c")})
+		assert.Equal(s.T(), withNewNode["node-a"], withoutNode["node-a"])
+		assert.Equal(s.T(), names["node-c"], withoutNode["node-c"])
+	})
+}
+
+func (s *CronSetSuite) TestNodeEvent_Create_NameCronJobsWithoutCollision() {
+	longNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "ip-10-0-123-45.eu-west-1.compute.internal",
+			Labels: map[string]string{"foo": "bar"},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, longNode))
+
+	s.Run("When reconcile a CronSet selecting a node with a long name", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should create a CronJob labeled and annotated with the node name", func() {
+			createdCronJob := &batchv1.CronJob{}
+			key := types.NamespacedName{Name: generateCronJobName(CronSetName, longNode.Name), Namespace: CronSetNamespace}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, createdCronJob))
+			assert.Equal(s.T(), longNode.Name, createdCronJob.Labels[NodeLabel])
+			assert.Equal(s.T(), longNode.Name, createdCronJob.Annotations[NodeNameAnnotation])
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(0), updatedCronSet.Status.NumberMisscheduled)
+			assert.Equal(s.T(), int32(2), updatedCronSet.Status.CurrentNumberScheduled)
+		})
+	})
+
+	s.Run("When nodes share the same NodeIdentificationKey annotation value", func() {
+		os.Setenv(NodeIdentificationKey, "xyz")
+		defer os.Unsetenv(NodeIdentificationKey)
+
+		createdNode := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: longNode.Name}, createdNode))
+		createdNode.Annotations = map[string]string{"xyz": "baz"}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdNode))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should create a distinct CronJob for every node", func() {
+			cronJobs, err := s.reconciler.listOwnedCronJobs(ctx, s.cronSet)
+			require.NoError(s.T(), err)
+			require.Len(s.T(), cronJobs, 2)
+			nodeNames := []string{
+				cronJobs[0].Spec.JobTemplate.Spec.Template.Spec.NodeName,
+				cronJobs[1].Spec.JobTemplate.Spec.Template.Spec.NodeName,
+			}
+			assert.ElementsMatch(s.T(), []string{s.node.Name, longNode.Name}, nodeNames)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Reconcile_UpdateConditions() {
+	getCronSet := func() *batchv1alpha1.CronSet {
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		return updatedCronSet
+	}
+	conditionStatus := func(cronSet *batchv1alpha1.CronSet, conditionType string) metav1.ConditionStatus {
+		condition := meta.FindStatusCondition(cronSet.Status.Conditions, conditionType)
+		require.NotNil(s.T(), condition, conditionType)
+		return condition.Status
+	}
+
+	s.Run("When reconcile a CronSet for the first time", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report the CronSet as available and progressing", func() {
+			updatedCronSet := getCronSet()
+			assert.Equal(s.T(), updatedCronSet.Generation, updatedCronSet.Status.ObservedGeneration)
+			assert.Equal(s.T(), metav1.ConditionTrue, conditionStatus(updatedCronSet, batchv1alpha1.CronSetAvailable))
+			assert.Equal(s.T(), metav1.ConditionTrue, conditionStatus(updatedCronSet, batchv1alpha1.CronSetProgressing))
+			assert.Equal(s.T(), metav1.ConditionFalse, conditionStatus(updatedCronSet, batchv1alpha1.CronSetDegraded))
+			assert.Equal(s.T(), metav1.ConditionFalse, conditionStatus(updatedCronSet, batchv1alpha1.CronSetNoEligibleNodes))
+		})
+	})
+
+	s.Run("When reconcile a CronSet without any change", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report the CronSet as no longer progressing", func() {
+			assert.Equal(s.T(), metav1.ConditionFalse, conditionStatus(getCronSet(), batchv1alpha1.CronSetProgressing))
+		})
+	})
+
+	s.Run("When the CronJob of a node can't be applied", func() {
+		failingNode := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "failing-node",
+				Labels: map[string]string{"foo": "bar"},
+			},
+		}
+		require.NoError(s.T(), s.fakeClient.Create(ctx, failingNode))
+		foreignCronJob := &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      generateCronJobName(CronSetName, failingNode.Name),
+				Namespace: CronSetNamespace,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "batch.grasse.io/v1alpha1", Kind: "CronSet", Name: "another-cronset", UID: "another-uid",
+					Controller: &trueVal,
+				}},
+			},
+			Spec: batchv1.CronJobSpec{Schedule: "1 * * * *"},
+		}
+		require.NoError(s.T(), s.fakeClient.Create(ctx, foreignCronJob))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report the CronSet as degraded with the failed node", func() {
+			updatedCronSet := getCronSet()
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.NumberMisscheduled)
+			assert.Equal(s.T(), metav1.ConditionFalse, conditionStatus(updatedCronSet, batchv1alpha1.CronSetAvailable))
+			degraded := meta.FindStatusCondition(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetDegraded)
+			require.NotNil(s.T(), degraded)
+			assert.Equal(s.T(), metav1.ConditionTrue, degraded.Status)
+			assert.Equal(s.T(), ReasonApplyFailed, degraded.Reason)
+			assert.Contains(s.T(), degraded.Message, failingNode.Name)
+		})
+
+		require.NoError(s.T(), s.fakeClient.Delete(ctx, failingNode))
+	})
+
+	s.Run("When no node matches the CronSet", func() {
+		createdCronSet := getCronSet()
+		createdCronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.NodeSelector = map[string]string{"foo": "bar1"}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report that there are no eligible nodes", func() {
+			updatedCronSet := getCronSet()
+			assert.Equal(s.T(), metav1.ConditionTrue, conditionStatus(updatedCronSet, batchv1alpha1.CronSetNoEligibleNodes))
+			assert.Equal(s.T(), metav1.ConditionFalse, conditionStatus(updatedCronSet, batchv1alpha1.CronSetDegraded))
+		})
+	})
+
+	s.Run("When the CronSet has an invalid selector", func() {
+		createdCronSet := getCronSet()
+		createdCronSet.Spec.Selector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "pool", Operator: "Unknown"}},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, createdCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.Error(s.T(), err)
+
+		s.Run("Should report the CronSet as degraded because of the invalid spec", func() {
+			degraded := meta.FindStatusCondition(getCronSet().Status.Conditions, batchv1alpha1.CronSetDegraded)
+			require.NotNil(s.T(), degraded)
+			assert.Equal(s.T(), metav1.ConditionTrue, degraded.Status)
+			assert.Equal(s.T(), ReasonInvalidSpec, degraded.Reason)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Reconcile_ReportNodeStatuses() {
+	failingNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "failing-node",
+			Labels: map[string]string{"foo": "bar"},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, failingNode))
+	require.NoError(s.T(), s.fakeClient.Create(ctx, &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      generateCronJobName(CronSetName, failingNode.Name),
+			Namespace: CronSetNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch.grasse.io/v1alpha1", Kind: "CronSet", Name: "another-cronset", UID: "another-uid",
+				Controller: &trueVal,
+			}},
+		},
+		Spec: batchv1.CronJobSpec{Schedule: "1 * * * *"},
+	}))
+
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	assert.NoError(s.T(), err)
+
+	s.Run("When a CronJob has been scheduled on a node", func() {
+		lastScheduleTime := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+		createdCronJob := &batchv1.CronJob{}
+		key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, key, createdCronJob))
+		createdCronJob.Status.LastScheduleTime = &lastScheduleTime
+		createdCronJob.Status.LastSuccessfulTime = &lastScheduleTime
+		require.NoError(s.T(), s.fakeClient.Status().Update(ctx, createdCronJob))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report every node with the failed nodes first", func() {
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			require.Len(s.T(), updatedCronSet.Status.Nodes, 2)
+
+			failedStatus := updatedCronSet.Status.Nodes[0]
+			assert.Equal(s.T(), failingNode.Name, failedStatus.NodeName)
+			assert.Equal(s.T(), generateCronJobName(CronSetName, failingNode.Name), failedStatus.CronJobName)
+			assert.Equal(s.T(), "AlreadyOwned", failedStatus.LastErrorReason)
+			assert.NotEmpty(s.T(), failedStatus.LastErrorMessage)
+
+			nodeStatus := updatedCronSet.Status.Nodes[1]
+			assert.Equal(s.T(), s.node.Name, nodeStatus.NodeName)
+			assert.Equal(s.T(), key.Name, nodeStatus.CronJobName)
+			assert.Equal(s.T(), computeTemplateHash(updatedCronSet), nodeStatus.TemplateHash)
+			assert.Empty(s.T(), nodeStatus.LastErrorReason)
+			assert.True(s.T(), lastScheduleTime.Equal(nodeStatus.LastScheduleTime))
+			assert.True(s.T(), lastScheduleTime.Equal(nodeStatus.LastSuccessfulTime))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestBuildNodeStatuses_LargeCluster_BoundSize() {
+	var cronJobs []batchv1.CronJob
+	for i := 0; i < maxNodeStatuses+50; i++ {
+		cronJob := batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cronjob-%03d", i)}}
+		cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName = fmt.Sprintf("node-%03d", i)
+		cronJobs = append(cronJobs, cronJob)
+	}
+	failures := map[string]nodeFailure{"node-299": {reason: ReasonApplyFailed, message: "boom"}}
+
+	nodeStatuses := buildNodeStatuses(cronJobs, nil, failures)
+
+	assert.Len(s.T(), nodeStatuses, maxNodeStatuses)
+	assert.Equal(s.T(), "node-299", nodeStatuses[0].NodeName)
+	assert.Equal(s.T(), "node-000", nodeStatuses[1].NodeName)
+}
+
+func (s *CronSetSuite) TestFailureMessage_LongErrors_BoundLength() {
+	failure := newNodeFailure(fmt.Errorf("webhook denied the request: %s", strings.Repeat("é", maxFailureMessageLength)))
+	failures := make(map[string]nodeFailure)
+	for i := 0; i < maxReportedFailures; i++ {
+		failures[fmt.Sprintf("node-%03d-%s", i, strings.Repeat("x", 240))] = failure
+	}
+
+	s.Run("Should truncate the error message of every node", func() {
+		assert.LessOrEqual(s.T(), len(failure.message), maxFailureMessageLength)
+		assert.True(s.T(), utf8.ValidString(failure.message))
+		assert.True(s.T(), strings.HasSuffix(failure.message, "..."))
+	})
+
+	s.Run("Should bound the length of the condition message", func() {
+		message := failureMessage(failures)
+		assert.LessOrEqual(s.T(), len(message), maxConditionMessageLength)
+		assert.True(s.T(), strings.HasPrefix(message, fmt.Sprintf("Failed to apply CronJobs on %d node(s): node-000-", maxReportedFailures)))
+		assert.Regexp(s.T(), "; and [0-9]+ more$", message)
+	})
+}
+
+// drainEvents returns the events recorded so far.
+func (s *CronSetSuite) drainEvents() []string {
+	var events []string
+	for {
+		select {
+		case event := <-s.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Reconcile_RecordEvents() {
+	s.Run("When a CronJob is created on a node", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should record a SuccessfulCreate event", func() {
+			events := s.drainEvents()
+			require.Len(s.T(), events, 1)
+			assert.Contains(s.T(), events[0], "Normal "+EventReasonSuccessfulCreate)
+			assert.Contains(s.T(), events[0], s.node.Name)
+		})
+	})
+
+	s.Run("When nothing changes", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should not record any event", func() {
+			assert.Empty(s.T(), s.drainEvents())
+		})
+	})
+
+	s.Run("When the CronJob template changes", func() {
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.CronJobTemplate.Spec.Schedule = "2 * * * *"
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should record a SuccessfulUpdate event", func() {
+			events := s.drainEvents()
+			require.Len(s.T(), events, 1)
+			assert.Contains(s.T(), events[0], "Normal "+EventReasonSuccessfulUpdate)
+		})
+	})
+
+	s.Run("When the node leaves the CronSet", func() {
+		require.NoError(s.T(), s.fakeClient.Delete(ctx, s.node))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should record a SuccessfulDelete event", func() {
+			events := s.drainEvents()
+			require.Len(s.T(), events, 1)
+			assert.Contains(s.T(), events[0], "Normal "+EventReasonSuccessfulDelete)
+			assert.Contains(s.T(), events[0], s.node.Name)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Reconcile_RecordFailedApplyEvents() {
+	require.NoError(s.T(), s.fakeClient.Create(ctx, &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      generateCronJobName(CronSetName, s.node.Name),
+			Namespace: CronSetNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch.grasse.io/v1alpha1", Kind: "CronSet", Name: "another-cronset", UID: "another-uid",
+				Controller: &trueVal,
+			}},
+		},
+		Spec: batchv1.CronJobSpec{Schedule: "1 * * * *"},
+	}))
+
+	s.Run("When the CronJob of a node can't be applied", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should record a warning on both the CronSet and the node", func() {
+			events := s.drainEvents()
+			require.Len(s.T(), events, 2)
+			for _, event := range events {
+				assert.Contains(s.T(), event, "Warning "+EventReasonFailedApply)
+			}
+		})
+	})
+}
+
+func (s *CronSetSuite) TestEventAggregator_ManyEvents_EmitSummary() {
+	recorder := record.NewFakeRecorder(100)
+	events := newEventAggregator(recorder)
+	for i := 0; i < maxEventsPerReason+1; i++ {
+		events.add(corev1.EventTypeNormal, EventReasonSuccessfulCreate, "Created CronJob %d", i)
+	}
+	events.add(corev1.EventTypeWarning, EventReasonFailedApply, "Failed")
+
+	events.flush(s.cronSet)
+
+	require.Len(s.T(), recorder.Events, 2)
+	assert.Equal(s.T(), fmt.Sprintf("Normal %s %d similar events, e.g. Created CronJob 0; Created CronJob 1; Created CronJob 2",
+		EventReasonSuccessfulCreate, maxEventsPerReason+1), <-recorder.Events)
+	assert.Equal(s.T(), "Warning "+EventReasonFailedApply+" Failed", <-recorder.Events)
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Reconcile_RecordMetrics() {
+	otherNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "other-node",
+			Labels: map[string]string{"foo": "bar"},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, otherNode))
+	require.NoError(s.T(), s.fakeClient.Create(ctx, &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      generateCronJobName(CronSetName, otherNode.Name),
+			Namespace: CronSetNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch.grasse.io/v1alpha1", Kind: "CronSet", Name: "another-cronset", UID: "another-uid",
+				Controller: &trueVal,
+			}},
+		},
+		Spec: batchv1.CronJobSpec{Schedule: "1 * * * *"},
+	}))
+	forgetCronSetMetrics(cronSetKey)
+
+	s.Run("When the CronJob of a node can't be applied", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report the CronJob counts and the failure", func() {
+			assert.Equal(s.T(), 2.0, testutil.ToFloat64(desiredCronJobs.WithLabelValues(CronSetNamespace, CronSetName)))
+			assert.Equal(s.T(), 1.0, testutil.ToFloat64(currentCronJobs.WithLabelValues(CronSetNamespace, CronSetName)))
+			assert.Equal(s.T(), 1.0, testutil.ToFloat64(misscheduledCronJobs.WithLabelValues(CronSetNamespace, CronSetName)))
+			assert.Equal(s.T(), 1.0, testutil.ToFloat64(applyFailuresTotal.WithLabelValues(CronSetNamespace, CronSetName, otherNode.Name, "AlreadyOwned")))
+			assert.Equal(s.T(), 1, testutil.CollectAndCount(reconcileDuration))
+		})
+	})
+
+	s.Run("When the CronSet is deleted", func() {
+		require.NoError(s.T(), s.fakeClient.Delete(ctx, s.cronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should drop the series of the CronSet", func() {
+			assert.Equal(s.T(), 0, testutil.CollectAndCount(desiredCronJobs))
+			assert.Equal(s.T(), 0, testutil.CollectAndCount(applyFailuresTotal))
+			assert.Equal(s.T(), 0, testutil.CollectAndCount(reconcileDuration))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestLastSuccessfulRunCollector_Scrape_ReportAge() {
+	now := time.Now()
+	collector := newLastSuccessfulRunCollector()
+	collector.now = func() time.Time { return now }
+
+	succeeded := batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "succeeded"}}
+	succeeded.Spec.JobTemplate.Spec.Template.Spec.NodeName = "node-a"
+	succeeded.Status.LastSuccessfulTime = &metav1.Time{Time: now.Add(-90 * time.Second)}
+	neverSucceeded := batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "never-succeeded"}}
+	neverSucceeded.Spec.JobTemplate.Spec.Template.Spec.NodeName = "node-b"
+	collector.set(cronSetKey, []batchv1.CronJob{succeeded, neverSucceeded})
+
+	expected := `
+# HELP cronset_last_successful_run_age_seconds Time since the last successful run of the CronJob of the CronSet on the node.
+# TYPE cronset_last_successful_run_age_seconds gauge
+cronset_last_successful_run_age_seconds{cronset="test-cronset",namespace="default",node="node-a"} 90
+`
+	assert.NoError(s.T(), testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	collector.forget(cronSetKey)
+	assert.Equal(s.T(), 0, testutil.CollectAndCount(collector))
+}
+
+// templateHashesByNode returns the template hash of the CronJob of every node.
+func (s *CronSetSuite) templateHashesByNode() map[string]string {
+	cronJobs := &batchv1.CronJobList{}
+	require.NoError(s.T(), s.fakeClient.List(ctx, cronJobs, client.InNamespace(CronSetNamespace)))
+	hashes := make(map[string]string)
+	for _, cronJob := range cronJobs.Items {
+		hashes[cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName] = cronJob.Labels[TemplateHashLabel]
+	}
+	return hashes
+}
+
+// createNodes adds nodes selected by the CronSet besides s.node.
+func (s *CronSetSuite) createNodes(names ...string) {
+	for _, name := range names {
+		require.NoError(s.T(), s.fakeClient.Create(ctx, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"foo": "bar"}},
+		}))
+	}
+}
+
+// changeTemplate changes the schedule of the CronSet and returns its new template hash.
+func (s *CronSetSuite) changeTemplate(updateStrategy *batchv1alpha1.CronSetUpdateStrategy) string {
+	updatedCronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+	updatedCronSet.Spec.CronJobTemplate.Spec.Schedule = "2 * * * *"
+	updatedCronSet.Spec.UpdateStrategy = updateStrategy
+	require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+	return computeTemplateHash(updatedCronSet)
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_RollingUpdate() {
+	s.createNodes("node-b", "node-c")
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	newHash := s.changeTemplate(&batchv1alpha1.CronSetUpdateStrategy{Type: batchv1alpha1.RollingUpdateCronSetStrategyType})
+
+	s.Run("When the template changes", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should update a single CronJob and report the rollout", func() {
+			hashes := s.templateHashesByNode()
+			assert.Equal(s.T(), newHash, hashes["node-b"])
+			assert.NotEqual(s.T(), newHash, hashes["node-c"])
+			assert.NotEqual(s.T(), newHash, hashes[s.node.Name])
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.UpdatedNumberScheduled)
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.NumberUnavailable)
+		})
+	})
+
+	s.Run("When the updated CronJob hasn't succeeded yet", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should not update more CronJobs", func() {
+			hashes := s.templateHashesByNode()
+			assert.NotEqual(s.T(), newHash, hashes["node-c"])
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			progressing := meta.FindStatusCondition(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetProgressing)
+			require.NotNil(s.T(), progressing)
+			assert.Equal(s.T(), metav1.ConditionTrue, progressing.Status)
+			assert.Equal(s.T(), ReasonRollingUpdate, progressing.Reason)
+		})
+	})
+
+	s.Run("When a job started from the previous template succeeds after the update", func() {
+		updatedCronJob := &batchv1.CronJob{}
+		key := types.NamespacedName{Name: generateCronJobName(CronSetName, "node-b"), Namespace: CronSetNamespace}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, key, updatedCronJob))
+		assert.NotEmpty(s.T(), updatedCronJob.Annotations[TemplateUpdatedAnnotation])
+		require.NoError(s.T(), s.fakeClient.Create(ctx, &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              updatedCronJob.Name + "-0",
+				Namespace:         CronSetNamespace,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "batch/v1", Kind: "CronJob", Name: updatedCronJob.Name, UID: updatedCronJob.UID, Controller: &trueVal,
+				}},
+			},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+			},
+		}))
+		updatedCronJob.Status.LastSuccessfulTime = &metav1.Time{Time: time.Now().Add(time.Minute)}
+		require.NoError(s.T(), s.fakeClient.Status().Update(ctx, updatedCronJob))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should not count it as a successful run of the new template", func() {
+			assert.NotEqual(s.T(), newHash, s.templateHashesByNode()["node-c"])
+		})
+	})
+
+	s.Run("When the updated CronJob succeeds", func() {
+		s.createCanaryJob("node-b", batchv1.JobComplete)
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should update the next CronJob", func() {
+			hashes := s.templateHashesByNode()
+			assert.Equal(s.T(), newHash, hashes["node-c"])
+			assert.NotEqual(s.T(), newHash, hashes[s.node.Name])
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_RollingUpdateWithPartition() {
+	s.createNodes("node-b", "node-c")
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	maxUnavailable := intstr.FromString("100%")
+	newHash := s.changeTemplate(&batchv1alpha1.CronSetUpdateStrategy{
+		Type: batchv1alpha1.RollingUpdateCronSetStrategyType,
+		RollingUpdate: &batchv1alpha1.RollingUpdateCronSet{
+			MaxUnavailable: &maxUnavailable,
+			Partition:      ptr.To[int32](2),
+		},
+	})
+
+	s.Run("When the template changes", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should only update the nodes beyond the partition", func() {
+			hashes := s.templateHashesByNode()
+			assert.NotEqual(s.T(), newHash, hashes["node-b"])
+			assert.NotEqual(s.T(), newHash, hashes["node-c"])
+			assert.Equal(s.T(), newHash, hashes[s.node.Name])
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_OnDelete() {
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	newHash := s.changeTemplate(&batchv1alpha1.CronSetUpdateStrategy{Type: batchv1alpha1.OnDeleteCronSetStrategyType})
+
+	s.Run("When the template changes", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should keep the CronJob on the previous template", func() {
+			assert.NotEqual(s.T(), newHash, s.templateHashesByNode()[s.node.Name])
+		})
+	})
+
+	s.Run("When the CronJob is deleted", func() {
+		key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+		require.NoError(s.T(), s.fakeClient.Delete(ctx, &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should recreate it from the current template", func() {
+			assert.Equal(s.T(), newHash, s.templateHashesByNode()[s.node.Name])
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplateOnDelete_ApplyNodeChanges() {
+	cronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+	cronSet.Spec.NodeRemovalGracePeriod = &metav1.Duration{Duration: 10 * time.Minute}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, cronSet))
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	previousHash := s.templateHashesByNode()[s.node.Name]
+	s.changeTemplate(&batchv1alpha1.CronSetUpdateStrategy{Type: batchv1alpha1.OnDeleteCronSetStrategyType})
+	_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+
+	key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+	updateNode := func(update func(node *corev1.Node)) *batchv1.CronJob {
+		node := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, node))
+		update(node)
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+		cronJob := &batchv1.CronJob{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+		return cronJob
+	}
+
+	s.Run("When the node of a held CronJob suspends every CronSet", func() {
+		cronJob := updateNode(func(node *corev1.Node) { node.Annotations = map[string]string{SuspendAnnotation: "*"} })
+
+		s.Run("Should suspend the CronJob and keep its previous template", func() {
+			assert.Equal(s.T(), ptr.To(true), cronJob.Spec.Suspend)
+			assert.Equal(s.T(), previousHash, cronJob.Labels[TemplateHashLabel])
+			assert.Equal(s.T(), "1 * * * *", cronJob.Spec.Schedule)
+		})
+	})
+
+	s.Run("When the node resumes the CronSets", func() {
+		cronJob := updateNode(func(node *corev1.Node) { node.Annotations = nil })
+
+		s.Run("Should resume the CronJob", func() {
+			assert.Nil(s.T(), cronJob.Spec.Suspend)
+			assert.Equal(s.T(), previousHash, cronJob.Labels[TemplateHashLabel])
+		})
+	})
+
+	s.Run("When the node comes back within the grace period", func() {
+		updateNode(func(node *corev1.Node) { node.Labels = map[string]string{"foo": "bar1"} })
+		cronJob := updateNode(func(node *corev1.Node) { node.Labels = map[string]string{"foo": "bar"} })
+
+		s.Run("Should resume the CronJob on its previous template", func() {
+			assert.Nil(s.T(), cronJob.Spec.Suspend)
+			assert.NotContains(s.T(), cronJob.Annotations, PendingDeletionAnnotation)
+			assert.Equal(s.T(), previousHash, cronJob.Labels[TemplateHashLabel])
+		})
+	})
+}
+
+// listRevisions returns the ControllerRevisions of the CronSet by template hash.
+func (s *CronSetSuite) listRevisions() map[string]appsv1.ControllerRevision {
+	revisionList := &appsv1.ControllerRevisionList{}
+	require.NoError(s.T(), s.fakeClient.List(ctx, revisionList, client.InNamespace(CronSetNamespace)))
+	revisions := make(map[string]appsv1.ControllerRevision)
+	for _, revision := range revisionList.Items {
+		revisions[revision.Labels[TemplateHashLabel]] = revision
+	}
+	return revisions
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_RecordRevisions() {
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	initialCronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, initialCronSet))
+	initialHash := computeTemplateHash(initialCronSet)
+
+	s.Run("When the CronSet is created", func() {
+		s.Run("Should snapshot the template into a ControllerRevision", func() {
+			revisions := s.listRevisions()
+			require.Len(s.T(), revisions, 1)
+			revision := revisions[initialHash]
+			assert.Equal(s.T(), revisionName(CronSetName, initialHash), revision.Name)
+			assert.Equal(s.T(), int64(1), revision.Revision)
+			assert.Equal(s.T(), expectedOwnerRefs, revision.OwnerReferences)
+			assert.Equal(s.T(), revision.Name, initialCronSet.Status.CurrentRevision)
+		})
+	})
+
+	newHash := s.changeTemplate(nil)
+
+	s.Run("When the template changes", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should record a new revision and keep the previous one", func() {
+			revisions := s.listRevisions()
+			require.Len(s.T(), revisions, 2)
+			assert.Equal(s.T(), int64(2), revisions[newHash].Revision)
+		})
+	})
+
+	s.Run("When rolling back to the first revision", func() {
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.RollbackTo = &batchv1alpha1.CronSetRollback{RevisionName: revisionName(CronSetName, initialHash)}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+		_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should restore the template and make its revision the latest one", func() {
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Nil(s.T(), updatedCronSet.Spec.RollbackTo)
+			assert.Equal(s.T(), "1 * * * *", updatedCronSet.Spec.CronJobTemplate.Spec.Schedule)
+			assert.Equal(s.T(), initialHash, s.templateHashesByNode()[s.node.Name])
+			assert.Equal(s.T(), int64(3), s.listRevisions()[initialHash].Revision)
+			assert.Contains(s.T(), s.drainEvents(), "Normal "+EventReasonRolledBack+" Rolled back to revision "+revisionName(CronSetName, initialHash))
+		})
+	})
+
+	s.Run("When rolling back to an unknown revision", func() {
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.RollbackTo = &batchv1alpha1.CronSetRollback{RevisionName: "unknown"}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should clear the rollback and record a warning", func() {
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Nil(s.T(), updatedCronSet.Spec.RollbackTo)
+			assert.Equal(s.T(), initialHash, computeTemplateHash(updatedCronSet))
+			assert.Contains(s.T(), s.drainEvents(), "Warning "+EventReasonRollbackRevisionNotFound+" Unable to find revision unknown of the CronSet")
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_PruneRevisions() {
+	s.cronSet.Spec.RevisionHistoryLimit = ptr.To[int32](1)
+	require.NoError(s.T(), s.fakeClient.Update(ctx, s.cronSet))
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	firstHash := s.templateHashesByNode()[s.node.Name]
+
+	s.Run("When the CronJob is held on an old revision", func() {
+		s.changeTemplate(&batchv1alpha1.CronSetUpdateStrategy{Type: batchv1alpha1.OnDeleteCronSetStrategyType})
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.CronJobTemplate.Spec.Schedule = "3 * * * *"
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+		_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+		thirdHash := computeTemplateHash(updatedCronSet)
+
+		s.Run("Should keep its revision besides the limit", func() {
+			revisions := s.listRevisions()
+			assert.Len(s.T(), revisions, 3)
+			assert.Contains(s.T(), revisions, firstHash)
+			assert.Contains(s.T(), revisions, thirdHash)
+		})
+
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.CronJobTemplate.Spec.Schedule = "4 * * * *"
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+		_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should delete the oldest unused revisions beyond the limit", func() {
+			revisions := s.listRevisions()
+			assert.Len(s.T(), revisions, 3)
+			assert.Contains(s.T(), revisions, firstHash)
+			assert.Contains(s.T(), revisions, thirdHash)
+			assert.Contains(s.T(), revisions, computeTemplateHash(updatedCronSet))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_HashCollision_RecordNewRevision() {
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	initialCronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, initialCronSet))
+	initialHash := computeTemplateHash(initialCronSet)
+
+	// Simulate another template with the same hash by changing the template stored in the revision.
+	revision := s.listRevisions()[initialHash]
+	collidingCronSet := initialCronSet.DeepCopy()
+	collidingCronSet.Spec.CronJobTemplate.Spec.Schedule = "2 * * * *"
+	data, err := json.Marshal(newTemplateSpec(collidingCronSet))
+	require.NoError(s.T(), err)
+	revision.Data.Raw = data
+	require.NoError(s.T(), s.fakeClient.Update(ctx, &revision))
+
+	s.Run("When the template hash matches a revision of another template", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.Error(s.T(), err)
+
+		s.Run("Should count the collision", func() {
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), ptr.To[int32](1), updatedCronSet.Status.CollisionCount)
+			assert.NotEqual(s.T(), initialHash, computeTemplateHash(updatedCronSet))
+		})
+	})
+
+	s.Run("When reconciling again", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should record the template in a revision of its own and roll it out", func() {
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			newHash := computeTemplateHash(updatedCronSet)
+			revisions := s.listRevisions()
+			require.Len(s.T(), revisions, 2)
+			assert.Equal(s.T(), int64(2), revisions[newHash].Revision)
+			assert.Equal(s.T(), revisionName(CronSetName, newHash), updatedCronSet.Status.CurrentRevision)
+			assert.Equal(s.T(), newHash, s.templateHashesByNode()[s.node.Name])
+		})
+	})
+}
+
+// createCanaryJob creates a finished job of the CronJob of the node.
+func (s *CronSetSuite) createCanaryJob(nodeName string, conditionType batchv1.JobConditionType) {
+	cronJob := &batchv1.CronJob{}
+	key := types.NamespacedName{Name: generateCronJobName(CronSetName, nodeName), Namespace: CronSetNamespace}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+	require.NoError(s.T(), s.fakeClient.Create(ctx, &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              cronJob.Name + "-1",
+			Namespace:         CronSetNamespace,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(time.Minute)),
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch/v1", Kind: "CronJob", Name: cronJob.Name, UID: cronJob.UID, Controller: &trueVal,
+			}},
+		},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}},
+		},
+	}))
+}
+
+func (s *CronSetSuite) startCanary() (string, string) {
+	s.createNodes("node-b", "node-c")
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	previousHash := s.templateHashesByNode()[s.node.Name]
+	maxUnavailable := intstr.FromString("100%")
+	newHash := s.changeTemplate(&batchv1alpha1.CronSetUpdateStrategy{
+		Type:          batchv1alpha1.RollingUpdateCronSetStrategyType,
+		RollingUpdate: &batchv1alpha1.RollingUpdateCronSet{MaxUnavailable: &maxUnavailable},
+		Canary:        &batchv1alpha1.CanaryStrategy{SuccessfulRuns: 1},
+	})
+	_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	s.drainEvents()
+	return previousHash, newHash
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_PromoteCanary() {
+	previousHash, newHash := s.startCanary()
+
+	s.Run("When the template changes", func() {
+		s.Run("Should only update the canary node", func() {
+			hashes := s.templateHashesByNode()
+			assert.Equal(s.T(), newHash, hashes["node-b"])
+			assert.Equal(s.T(), previousHash, hashes["node-c"])
+			assert.Equal(s.T(), previousHash, hashes[s.node.Name])
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			require.NotNil(s.T(), updatedCronSet.Status.Canary)
+			assert.Equal(s.T(), batchv1alpha1.CanaryPhaseProgressing, updatedCronSet.Status.Canary.Phase)
+			assert.Equal(s.T(), revisionName(CronSetName, previousHash), updatedCronSet.Status.Canary.PreviousRevision)
+			condition := meta.FindStatusCondition(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetCanaryPromoted)
+			require.NotNil(s.T(), condition)
+			assert.Equal(s.T(), ReasonCanaryInProgress, condition.Reason)
+		})
+	})
+
+	s.Run("When the canary job succeeds", func() {
+		s.createCanaryJob("node-b", batchv1.JobComplete)
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should promote the template to the other nodes", func() {
+			hashes := s.templateHashesByNode()
+			assert.Equal(s.T(), newHash, hashes["node-c"])
+			assert.Equal(s.T(), newHash, hashes[s.node.Name])
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), batchv1alpha1.CanaryPhasePromoted, updatedCronSet.Status.Canary.Phase)
+			assert.True(s.T(), meta.IsStatusConditionTrue(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetCanaryPromoted))
+			assert.Contains(s.T(), strings.Join(s.drainEvents(), "\n"), "Normal "+EventReasonCanaryPromoted)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_RollBackFailedCanary() {
+	previousHash, _ := s.startCanary()
+
+	s.Run("When the canary job fails", func() {
+		s.createCanaryJob("node-b", batchv1.JobFailed)
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should roll the canary node back to the previous template", func() {
+			hashes := s.templateHashesByNode()
+			assert.Equal(s.T(), previousHash, hashes["node-b"])
+			assert.Equal(s.T(), previousHash, hashes["node-c"])
+			assert.Equal(s.T(), previousHash, hashes[s.node.Name])
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), batchv1alpha1.CanaryPhaseFailed, updatedCronSet.Status.Canary.Phase)
+			condition := meta.FindStatusCondition(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetCanaryPromoted)
+			require.NotNil(s.T(), condition)
+			assert.Equal(s.T(), metav1.ConditionFalse, condition.Status)
+			assert.Equal(s.T(), ReasonCanaryFailed, condition.Reason)
+			degraded := meta.FindStatusCondition(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetDegraded)
+			require.NotNil(s.T(), degraded)
+			assert.Equal(s.T(), metav1.ConditionTrue, degraded.Status)
+			assert.Equal(s.T(), ReasonCanaryFailed, degraded.Reason)
+			assert.Contains(s.T(), strings.Join(s.drainEvents(), "\n"), "Warning "+EventReasonCanaryFailed)
+		})
+	})
+
+	s.Run("When reconciling again", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should keep every node on the previous template", func() {
+			for nodeName, hash := range s.templateHashesByNode() {
+				assert.Equal(s.T(), previousHash, hash, nodeName)
+			}
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_UpdateTemplate_TimeOutCanary() {
+	s.createNodes("node-b")
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	previousHash := s.templateHashesByNode()[s.node.Name]
+	s.changeTemplate(&batchv1alpha1.CronSetUpdateStrategy{
+		Type: batchv1alpha1.RollingUpdateCronSetStrategyType,
+		Canary: &batchv1alpha1.CanaryStrategy{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}},
+			Timeout:  &metav1.Duration{Duration: time.Hour},
+		},
+	})
+	getCronSet := func() *batchv1alpha1.CronSet {
+		cronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+		return cronSet
+	}
+
+	s.Run("When no eligible node matches the canary", func() {
+		result, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should hold every node, report the CronSet as degraded and requeue until the timeout", func() {
+			for nodeName, hash := range s.templateHashesByNode() {
+				assert.Equal(s.T(), previousHash, hash, nodeName)
+			}
+			cronSet := getCronSet()
+			assert.Equal(s.T(), batchv1alpha1.CanaryPhaseProgressing, cronSet.Status.Canary.Phase)
+			assert.NotNil(s.T(), cronSet.Status.Canary.StartTime)
+			degraded := meta.FindStatusCondition(cronSet.Status.Conditions, batchv1alpha1.CronSetDegraded)
+			require.NotNil(s.T(), degraded)
+			assert.Equal(s.T(), metav1.ConditionTrue, degraded.Status)
+			assert.Equal(s.T(), ReasonCanaryNodesMissing, degraded.Reason)
+			assert.Greater(s.T(), result.RequeueAfter, time.Duration(0))
+			assert.LessOrEqual(s.T(), result.RequeueAfter, time.Hour)
+		})
+	})
+
+	s.Run("When the canary times out", func() {
+		cronSet := getCronSet()
+		cronSet.Status.Canary.StartTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+		require.NoError(s.T(), s.fakeClient.Status().Update(ctx, cronSet))
+		s.drainEvents()
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should fail the canary and keep every node on the previous template", func() {
+			cronSet := getCronSet()
+			assert.Equal(s.T(), batchv1alpha1.CanaryPhaseFailed, cronSet.Status.Canary.Phase)
+			assert.Contains(s.T(), cronSet.Status.Canary.Message, "Timed out after 1h0m0s")
+			degraded := meta.FindStatusCondition(cronSet.Status.Conditions, batchv1alpha1.CronSetDegraded)
+			require.NotNil(s.T(), degraded)
+			assert.Equal(s.T(), ReasonCanaryFailed, degraded.Reason)
+			assert.Contains(s.T(), strings.Join(s.drainEvents(), "\n"), "Warning "+EventReasonCanaryFailed)
+			for nodeName, hash := range s.templateHashesByNode() {
+				assert.Equal(s.T(), previousHash, hash, nodeName)
+			}
+		})
+	})
+}
+
+func (s *CronSetSuite) TestRenderSchedule_HashTokens_ExpandPerNode() {
+	cronSet := s.cronSet.DeepCopy()
+
+	s.Run("When the schedule has H tokens", func() {
+		cronSet.Spec.CronJobTemplate.Spec.Schedule = "H H(2-4) * * H"
+		schedule, err := renderSchedule(cronSet, "node-a")
+		require.NoError(s.T(), err)
+
+		s.Run("Should replace them with values within their range", func() {
+			fields := strings.Fields(schedule)
+			require.Len(s.T(), fields, 5)
+			minute, _ := strconv.Atoi(fields[0])
+			hour, _ := strconv.Atoi(fields[1])
+			dayOfWeek, _ := strconv.Atoi(fields[4])
+			assert.True(s.T(), minute >= 0 && minute <= 59, schedule)
+			assert.True(s.T(), hour >= 2 && hour <= 4, schedule)
+			assert.True(s.T(), dayOfWeek >= 0 && dayOfWeek <= 6, schedule)
+			assert.Equal(s.T(), "* *", strings.Join(fields[2:4], " "))
+		})
+
+		s.Run("Should be stable for a node", func() {
+			again, err := renderSchedule(cronSet, "node-a")
+			require.NoError(s.T(), err)
+			assert.Equal(s.T(), schedule, again)
+		})
+	})
+
+	s.Run("When the schedule has a H step", func() {
+		cronSet.Spec.CronJobTemplate.Spec.Schedule = "H/15 * * * *"
+		schedule, err := renderSchedule(cronSet, "node-a")
+		require.NoError(s.T(), err)
+
+		s.Run("Should start the step within the first interval", func() {
+			var start int
+			_, err := fmt.Sscanf(schedule, "%d-59/15 * * * *", &start)
+			require.NoError(s.T(), err, schedule)
+			assert.True(s.T(), start >= 0 && start < 15, schedule)
+		})
+	})
+
+	for _, weekdays := range []string{"THU", "MON-THU", "TUE,THU"} {
+		s.Run("When the schedule has the weekday names "+weekdays, func() {
+			cronSet.Spec.CronJobTemplate.Spec.Schedule = "0 3 * * " + weekdays
+			schedule, err := renderSchedule(cronSet, "node-a")
+			require.NoError(s.T(), err)
+
+			s.Run("Should leave them unchanged", func() {
+				assert.Equal(s.T(), "0 3 * * "+weekdays, schedule)
+			})
+		})
+	}
+
+	s.Run("When the schedule has H tokens and weekday names", func() {
+		cronSet.Spec.CronJobTemplate.Spec.Schedule = "H 3 * * THU"
+		schedule, err := renderSchedule(cronSet, "node-a")
+		require.NoError(s.T(), err)
+
+		s.Run("Should only replace the H tokens", func() {
+			var minute int
+			_, err := fmt.Sscanf(schedule, "%d 3 * * THU", &minute)
+			require.NoError(s.T(), err, schedule)
+			assert.True(s.T(), strings.HasSuffix(schedule, " 3 * * THU"), schedule)
+		})
+	})
+
+	s.Run("When the schedule has an invalid H token", func() {
+		cronSet.Spec.CronJobTemplate.Spec.Schedule = "H(50-70) * * * *"
+		_, err := renderSchedule(cronSet, "node-a")
+
+		s.Run("Should return an error", func() {
+			assert.Error(s.T(), err)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestRenderSchedule_Stagger_ShiftSchedule() {
+	cronSet := s.cronSet.DeepCopy()
+	cronSet.Spec.Stagger = &batchv1alpha1.Stagger{Window: metav1.Duration{Duration: 2 * time.Hour}}
+	offset := int(scheduleHash("node-a", "stagger") % 120)
+
+	testCases := []struct {
+		schedule string
+		expected string
+	}{
+		{"30 23 * * *", fmt.Sprintf("%d %d * * *", (30+offset)%60, (23*60+30+offset)%1440/60)},
+		{"@daily", fmt.Sprintf("%d %d * * *", offset%60, offset/60)},
+		{"30 23 * * 0", fmt.Sprintf("%d 23 * * 0", 30+offset%30)},
+		{"@weekly", fmt.Sprintf("%d %d * * 0", offset%60, offset/60)},
+		{"30 3 * * THU", fmt.Sprintf("%d %d * * THU", (30+offset)%60, (3*60+30+offset)/60)},
+		{"5 * * * *", fmt.Sprintf("%d * * * *", (5+offset)%60)},
+		{"*/15 * * * *", fmt.Sprintf("%d/15 * * * *", offset%15)},
+		{"* * * * *", "* * * * *"},
+		{"@every 1h", "@every 1h"},
+	}
+	for _, testCase := range testCases {
+		s.Run("When the schedule is "+testCase.schedule, func() {
+			cronSet.Spec.CronJobTemplate.Spec.Schedule = testCase.schedule
+			schedule, err := renderSchedule(cronSet, "node-a")
+			require.NoError(s.T(), err)
+
+			s.Run("Should shift it by the offset of the node", func() {
+				assert.Equal(s.T(), testCase.expected, schedule)
+			})
+		})
+	}
+
+	s.Run("When the window changes", func() {
+		widerCronSet := cronSet.DeepCopy()
+		widerCronSet.Spec.Stagger.Window.Duration = 4 * time.Hour
+
+		s.Run("Should change the template hash", func() {
+			assert.NotEqual(s.T(), computeTemplateHash(cronSet), computeTemplateHash(widerCronSet))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Create_StaggerSchedules() {
+	s.createNodes("node-b")
+	s.cronSet.Spec.Stagger = &batchv1alpha1.Stagger{Window: metav1.Duration{Duration: 30 * time.Minute}}
+	s.cronSet.Spec.CronJobTemplate.Spec.Schedule = "0 * * * *"
+	require.NoError(s.T(), s.fakeClient.Update(ctx, s.cronSet))
+
+	s.Run("When creating the CronJobs", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should give every node its staggered schedule", func() {
+			for _, nodeName := range []string{"node-b", s.node.Name} {
+				cronJob := &batchv1.CronJob{}
+				key := types.NamespacedName{Name: generateCronJobName(CronSetName, nodeName), Namespace: CronSetNamespace}
+				require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+				expected, err := renderSchedule(s.cronSet, nodeName)
+				require.NoError(s.T(), err)
+				assert.Equal(s.T(), expected, cronJob.Spec.Schedule)
+			}
+		})
+	})
+
+	s.Run("When the schedule has an invalid H token", func() {
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.CronJobTemplate.Spec.Schedule = "H(9-1) * * * *"
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+
+		s.Run("Should report the invalid spec", func() {
+			assert.Error(s.T(), err)
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			degraded := meta.FindStatusCondition(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetDegraded)
+			require.NotNil(s.T(), degraded)
+			assert.Equal(s.T(), ReasonInvalidSpec, degraded.Reason)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Create_RenderNodeAttributes() {
+	s.node.Labels["topology.kubernetes.io/zone"] = "zone-a"
+	require.NoError(s.T(), s.fakeClient.Update(ctx, s.node))
+	podSpec := &s.cronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec
+	podSpec.Containers[0].Command = []string{"/bin/collect", "--node={{ .Node.Name }}"}
+	podSpec.Containers[0].Args = []string{`{{ index .Node.Annotations "xyz" }}`}
+	podSpec.Containers[0].Env = []corev1.EnvVar{
+		{Name: "ZONE", Value: `{{ index .Node.Labels "topology.kubernetes.io/zone" }}`},
+		{Name: "PLAIN", Value: "unchanged"},
+	}
+	podSpec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "logs", MountPath: "/logs", SubPath: "{{ .Node.Name }}"}}
+	podSpec.Volumes = []corev1.Volume{{
+		Name:         "logs",
+		VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/log/{{ .Node.Name }}"}},
+	}}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, s.cronSet))
+
+	s.Run("When creating the CronJob of a node", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should render the node attributes into the pod template", func() {
+			cronJob := &batchv1.CronJob{}
+			key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			renderedSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+			assert.Equal(s.T(), []string{"/bin/collect", "--node=test-node"}, renderedSpec.Containers[0].Command)
+			assert.Equal(s.T(), []string{"baz"}, renderedSpec.Containers[0].Args)
+			assert.Equal(s.T(), []corev1.EnvVar{{Name: "ZONE", Value: "zone-a"}, {Name: "PLAIN", Value: "unchanged"}}, renderedSpec.Containers[0].Env)
+			assert.Equal(s.T(), "test-node", renderedSpec.Containers[0].VolumeMounts[0].SubPath)
+			assert.Equal(s.T(), "/var/log/test-node", renderedSpec.Volumes[0].HostPath.Path)
+		})
+	})
+
+	s.Run("When the template can't be rendered", func() {
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env[0].Value = "{{ .Node.Zone }}"
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report the failure of the node", func() {
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			require.NotEmpty(s.T(), updatedCronSet.Status.Nodes)
+			nodeStatus := updatedCronSet.Status.Nodes[0]
+			assert.Equal(s.T(), s.node.Name, nodeStatus.NodeName)
+			assert.Equal(s.T(), ReasonTemplateRenderFailed, nodeStatus.LastErrorReason)
+			assert.Contains(s.T(), nodeStatus.LastErrorMessage, "containers[test-container-1].env[ZONE].value")
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.NumberMisscheduled)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Create_ApplyOverrides() {
+	armNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "arm-node",
+			Labels: map[string]string{"foo": "bar", "kubernetes.io/arch": "arm64", "size": "big"},
+		},
+	}
+	bigNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "big-node",
+			Labels: map[string]string{"foo": "bar", "size": "big"},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, armNode))
+	require.NoError(s.T(), s.fakeClient.Create(ctx, bigNode))
+	s.cronSet.Spec.Overrides = []batchv1alpha1.CronJobTemplateOverride{
+		{
+			Name:      "arm64",
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/arch": "arm64"}},
+			PatchType: batchv1alpha1.OverridePatchStrategicMerge,
+			Patch: runtime.RawExtension{Raw: []byte(`{"metadata":{"labels":{"arch":"arm64"}},` +
+				`"spec":{"jobTemplate":{"spec":{"template":{"spec":{"containers":[{"name":"test-container-1","image":"test-image-arm64"}]}}}}}}`)},
+		},
+		{
+			Name:      "big",
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"size": "big"}},
+			PatchType: batchv1alpha1.OverridePatchJSON,
+			Patch:     runtime.RawExtension{Raw: []byte(`[{"op":"add","path":"/spec/jobTemplate/spec/template/spec/containers/0/args","value":["--big"]}]`)},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, s.cronSet))
+
+	s.Run("When creating the CronJobs", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		getCronJob := func(nodeName string) *batchv1.CronJob {
+			cronJob := &batchv1.CronJob{}
+			key := types.NamespacedName{Name: generateCronJobName(CronSetName, nodeName), Namespace: CronSetNamespace}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			return cronJob
+		}
+
+		s.Run("Should apply the first matching strategic merge patch", func() {
+			cronJob := getCronJob(armNode.Name)
+			container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+			assert.Equal(s.T(), "test-image-arm64", container.Image)
+			assert.Empty(s.T(), container.Args)
+			assert.Equal(s.T(), "arm64", cronJob.Labels["arch"])
+			assert.Equal(s.T(), "arm64", cronJob.Annotations[OverrideAnnotation])
+			assert.Equal(s.T(), computeTemplateHash(s.cronSet), cronJob.Labels[TemplateHashLabel])
+		})
+
+		s.Run("Should apply the JSON patch", func() {
+			cronJob := getCronJob(bigNode.Name)
+			container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+			assert.Equal(s.T(), "test-image", container.Image)
+			assert.Equal(s.T(), []string{"--big"}, container.Args)
+			assert.Equal(s.T(), "big", cronJob.Annotations[OverrideAnnotation])
+		})
+
+		s.Run("Should keep the template for the other nodes", func() {
+			cronJob := getCronJob(s.node.Name)
+			assert.Equal(s.T(), "test-image", cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image)
+			assert.NotContains(s.T(), cronJob.Annotations, OverrideAnnotation)
+		})
+	})
+
+	s.Run("When a patch can't be applied", func() {
+		updatedCronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+		updatedCronSet.Spec.Overrides[1].Patch = runtime.RawExtension{Raw: []byte(`[{"op":"replace","path":"/spec/missing/0","value":1}]`)}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, updatedCronSet))
+
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		assert.NoError(s.T(), err)
+
+		s.Run("Should report the failure of the matching nodes only", func() {
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.NumberMisscheduled)
+			nodeStatus := updatedCronSet.Status.Nodes[0]
+			assert.Equal(s.T(), bigNode.Name, nodeStatus.NodeName)
+			assert.Equal(s.T(), ReasonOverrideFailed, nodeStatus.LastErrorReason)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestNodeEvent_UpdateAnnotations_SuspendOrExcludeNode() {
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+
+	annotateNode := func(annotation, value string) {
+		node := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, node))
+		node.Annotations = map[string]string{annotation: value}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		assert.Equal(s.T(), []reconcile.Request{{NamespacedName: cronSetKey}}, s.reconciler.findCronSetsForNode(ctx, node))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+	}
+
+	s.Run("When the node suspends another CronSet", func() {
+		annotateNode(SuspendAnnotation, "other-cronset")
+
+		s.Run("Should keep the CronJob running", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			assert.Nil(s.T(), cronJob.Spec.Suspend)
+		})
+	})
+
+	s.Run("When the node suspends every CronSet", func() {
+		annotateNode(SuspendAnnotation, "*")
+
+		s.Run("Should suspend the CronJob", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			assert.Equal(s.T(), ptr.To(true), cronJob.Spec.Suspend)
+		})
+	})
+
+	s.Run("When the node excludes the CronSet", func() {
+		annotateNode(ExcludeAnnotation, "other-cronset, "+CronSetNamespace+"/"+CronSetName)
+
+		s.Run("Should delete the CronJob and report the node as opted out", func() {
+			err := s.fakeClient.Get(ctx, key, &batchv1.CronJob{})
+			assert.True(s.T(), errors.IsNotFound(err))
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), []batchv1alpha1.ExcludedNode{{Name: s.node.Name, Reason: batchv1alpha1.ExcludedNodeReasonOptedOut}},
+				updatedCronSet.Status.ExcludedNodes)
+		})
+	})
+
+	s.Run("When the annotations are removed", func() {
+		annotateNode("xyz", "baz")
+
+		s.Run("Should create the CronJob again without suspending it", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			assert.Nil(s.T(), cronJob.Spec.Suspend)
+		})
+	})
+}
+
+// createRunningJob creates an unfinished job of the CronJob of the node.
+func (s *CronSetSuite) createRunningJob(nodeName string) *batchv1.Job {
+	cronJob := &batchv1.CronJob{}
+	key := types.NamespacedName{Name: generateCronJobName(CronSetName, nodeName), Namespace: CronSetNamespace}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cronJob.Name + "-1",
+			Namespace: CronSetNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch/v1", Kind: "CronJob", Name: cronJob.Name, UID: cronJob.UID, Controller: &trueVal,
+			}},
+		},
+	}
+	require.NoError(s.T(), s.fakeClient.Create(ctx, job))
+	return job
+}
+
+// finishJob marks the job as complete.
+func (s *CronSetSuite) finishJob(job *batchv1.Job) {
+	require.NoError(s.T(), s.fakeClient.Get(ctx, client.ObjectKeyFromObject(job), job))
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	require.NoError(s.T(), s.fakeClient.Status().Update(ctx, job))
+}
+
+func (s *CronSetSuite) TestJobEvent_Running_DelayNodeTermination() {
+	cronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+	cronSet.Spec.TerminationProtection = &batchv1alpha1.TerminationProtection{MaxDelay: &metav1.Duration{Duration: 10 * time.Minute}}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, cronSet))
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+
+	cronJobName := generateCronJobName(CronSetName, s.node.Name)
+	cronJob := &batchv1.CronJob{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: cronJobName, Namespace: CronSetNamespace}, cronJob))
+	job := s.createRunningJob(s.node.Name)
+	pdbKey := types.NamespacedName{Name: cronJobName, Namespace: CronSetNamespace}
+	getNode := func() *corev1.Node {
+		node := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, node))
+		return node
+	}
+
+	s.Run("When a job of the node is running", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should protect the pods and the node", func() {
+			assert.Equal(s.T(), protectedPodLabels(cronSet, s.node.Name), cronJob.Spec.JobTemplate.Spec.Template.Labels)
+
+			pdb := &policyv1.PodDisruptionBudget{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, pdbKey, pdb))
+			assert.Equal(s.T(), intstr.FromInt32(0), *pdb.Spec.MaxUnavailable)
+			assert.Equal(s.T(), protectedPodLabels(cronSet, s.node.Name), pdb.Spec.Selector.MatchLabels)
+			assert.True(s.T(), metav1.IsControlledBy(pdb, cronSet))
+
+			assert.Contains(s.T(), getNode().Finalizers, nodeFinalizer(cronSet))
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Contains(s.T(), updatedCronSet.Finalizers, NodeProtectionFinalizer)
+		})
+	})
+
+	s.Run("When the node is cordoned", func() {
+		node := getNode()
+		node.Spec.Unschedulable = true
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		result, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should keep the node protected until the maximum delay", func() {
+			node := getNode()
+			assert.Contains(s.T(), node.Finalizers, nodeFinalizer(cronSet))
+			assert.Contains(s.T(), node.Annotations, DrainingSinceAnnotation)
+			assert.NoError(s.T(), s.fakeClient.Get(ctx, pdbKey, &policyv1.PodDisruptionBudget{}))
+			assert.Greater(s.T(), result.RequeueAfter, 9*time.Minute)
+			assert.LessOrEqual(s.T(), result.RequeueAfter, 10*time.Minute)
+		})
+	})
+
+	s.Run("When the maximum delay passed", func() {
+		node := getNode()
+		node.Annotations[DrainingSinceAnnotation] = time.Now().Add(-11 * time.Minute).UTC().Format(time.RFC3339)
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		s.drainEvents()
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should release the node although the job is running", func() {
+			assert.NotContains(s.T(), getNode().Finalizers, nodeFinalizer(cronSet))
+			assert.True(s.T(), errors.IsNotFound(s.fakeClient.Get(ctx, pdbKey, &policyv1.PodDisruptionBudget{})))
+			assert.Contains(s.T(), strings.Join(s.drainEvents(), "\n"), "Warning "+EventReasonTerminationDelayExceeded)
+		})
+	})
+
+	s.Run("When the node is uncordoned and the job finishes", func() {
+		node := getNode()
+		node.Spec.Unschedulable = false
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+		assert.Contains(s.T(), getNode().Finalizers, nodeFinalizer(cronSet))
+
+		s.finishJob(job)
+		_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should release the node", func() {
+			node := getNode()
+			assert.NotContains(s.T(), node.Finalizers, nodeFinalizer(cronSet))
+			assert.NotContains(s.T(), node.Annotations, DrainingSinceAnnotation)
+			assert.True(s.T(), errors.IsNotFound(s.fakeClient.Get(ctx, pdbKey, &policyv1.PodDisruptionBudget{})))
+		})
+	})
+
+	s.Run("When the CronSet is deleted while protecting the node", func() {
+		require.NoError(s.T(), s.fakeClient.Get(ctx, client.ObjectKeyFromObject(job), job))
+		job.Status.Conditions = nil
+		require.NoError(s.T(), s.fakeClient.Status().Update(ctx, job))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+		require.Contains(s.T(), getNode().Finalizers, nodeFinalizer(cronSet))
+
+		require.NoError(s.T(), s.fakeClient.Delete(ctx, cronSet))
+		_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should release the node before the CronSet is removed", func() {
+			assert.NotContains(s.T(), getNode().Finalizers, nodeFinalizer(cronSet))
+			assert.True(s.T(), errors.IsNotFound(s.fakeClient.Get(ctx, cronSetKey, &batchv1alpha1.CronSet{})))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestJobEvent_Running_DisableScaleDown() {
+	setPolicy := func(policy batchv1alpha1.ScaleDownProtectionPolicy) {
+		cronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+		cronSet.Spec.ScaleDownProtection = policy
+		require.NoError(s.T(), s.fakeClient.Update(ctx, cronSet))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+	}
+	getNode := func() *corev1.Node {
+		node := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, node))
+		return node
+	}
+
+	s.Run("When the policy is Pod", func() {
+		setPolicy(batchv1alpha1.ScaleDownProtectionPod)
+
+		s.Run("Should annotate the pod template", func() {
+			cronJob := &batchv1.CronJob{}
+			key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			annotations := cronJob.Spec.JobTemplate.Spec.Template.Annotations
+			assert.Equal(s.T(), "false", annotations[SafeToEvictAnnotation])
+			assert.Equal(s.T(), "true", annotations[DoNotDisruptAnnotation])
+		})
+	})
+
+	node := getNode()
+	node.Annotations[DoNotDisruptAnnotation] = "true"
+	require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+	setPolicy(batchv1alpha1.ScaleDownProtectionNode)
+	job := s.createRunningJob(s.node.Name)
+
+	s.Run("When a job of the node is running", func() {
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should annotate the node and record the annotations it added", func() {
+			annotations := getNode().Annotations
+			assert.Equal(s.T(), CronSetNamespace+"/"+CronSetName, annotations[ScaleDownDisabledByAnnotation])
+			assert.Equal(s.T(), "true", annotations[ScaleDownDisabledAnnotation])
+			assert.Equal(s.T(), "true", annotations[DoNotDisruptAnnotation])
+			assert.Equal(s.T(), ScaleDownDisabledAnnotation, annotations[ScaleDownAnnotationsAnnotation])
+		})
+	})
+
+	s.Run("When the job finishes while another CronSet runs a job", func() {
+		node := getNode()
+		node.Annotations[ScaleDownDisabledByAnnotation] += ",kube-system/other-cronset"
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		s.finishJob(job)
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should keep the annotations of the other CronSet", func() {
+			annotations := getNode().Annotations
+			assert.Equal(s.T(), "kube-system/other-cronset", annotations[ScaleDownDisabledByAnnotation])
+			assert.Equal(s.T(), "true", annotations[ScaleDownDisabledAnnotation])
+		})
+	})
+
+	s.Run("When the last CronSet is removed", func() {
+		node := getNode()
+		node.Annotations[ScaleDownDisabledByAnnotation] = CronSetNamespace + "/" + CronSetName
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should remove the annotations it added and keep the pre-existing ones", func() {
+			annotations := getNode().Annotations
+			assert.NotContains(s.T(), annotations, ScaleDownDisabledByAnnotation)
+			assert.NotContains(s.T(), annotations, ScaleDownAnnotationsAnnotation)
+			assert.NotContains(s.T(), annotations, ScaleDownDisabledAnnotation)
+			assert.Equal(s.T(), "true", annotations[DoNotDisruptAnnotation])
+		})
+	})
+}
+
+func (s *CronSetSuite) TestNodeEvent_Remove_RunNodeRemovalHook() {
+	s.createNodes("node-b")
+	cronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+	cronSet.Spec.OnNodeRemoval = &batchv1alpha1.NodeRemovalHook{
+		JobTemplate: batchv1.JobTemplateSpec{
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers:    []corev1.Container{{Name: "flush", Image: "flush", Args: []string{"--node={{ .Node.Name }}"}}},
+						RestartPolicy: corev1.RestartPolicyNever,
+					},
+				},
+			},
+		},
+		Timeout: &metav1.Duration{Duration: 2 * time.Minute},
+	}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, cronSet))
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+
+	removeNode := func(name string) {
+		node := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: name}, node))
+		node.Labels = map[string]string{"foo": "bar1"}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+	}
+	cronJobKey := func(nodeName string) types.NamespacedName {
+		return types.NamespacedName{Name: generateCronJobName(CronSetName, nodeName), Namespace: CronSetNamespace}
+	}
+	hookKey := func(nodeName string) types.NamespacedName {
+		return types.NamespacedName{Name: nodeRemovalHookJobName(cronJobKey(nodeName).Name), Namespace: CronSetNamespace}
+	}
+
+	s.Run("When a node leaves the CronSet", func() {
+		s.drainEvents()
+		removeNode(s.node.Name)
+
+		s.Run("Should run the hook on the node and keep the CronJob", func() {
+			job := &batchv1.Job{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, hookKey(s.node.Name), job))
+			assert.Equal(s.T(), s.node.Name, job.Spec.Template.Spec.NodeName)
+			assert.Equal(s.T(), []string{"--node=" + s.node.Name}, job.Spec.Template.Spec.Containers[0].Args)
+			assert.Equal(s.T(), ptr.To(int64(120)), job.Spec.ActiveDeadlineSeconds)
+			assert.Equal(s.T(), cronJobKey(s.node.Name).Name, job.Labels[NodeRemovalHookLabel])
+			assert.True(s.T(), metav1.IsControlledBy(job, cronSet))
+			assert.Equal(s.T(), []reconcile.Request{{NamespacedName: cronSetKey}}, s.reconciler.findCronSetForJob(ctx, job))
+			assert.Contains(s.T(), strings.Join(s.drainEvents(), "\n"), "Normal "+EventReasonNodeRemovalHookStarted)
+
+			assert.NoError(s.T(), s.fakeClient.Get(ctx, cronJobKey(s.node.Name), &batchv1.CronJob{}))
+		})
+	})
+
+	s.Run("When the hook finishes", func() {
+		s.finishJob(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: hookKey(s.node.Name).Name, Namespace: CronSetNamespace}})
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should delete the CronJob and the hook Job", func() {
+			err := s.fakeClient.Get(ctx, cronJobKey(s.node.Name), &batchv1.CronJob{})
+			assert.True(s.T(), errors.IsNotFound(err))
+			err = s.fakeClient.Get(ctx, hookKey(s.node.Name), &batchv1.Job{})
+			assert.True(s.T(), errors.IsNotFound(err))
+		})
+	})
+
+	s.Run("When the node is gone before the hook finishes", func() {
+		removeNode("node-b")
+		require.NoError(s.T(), s.fakeClient.Get(ctx, hookKey("node-b"), &batchv1.Job{}))
+		require.NoError(s.T(), s.fakeClient.Delete(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}}))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should delete the CronJob and leave no hook Job behind", func() {
+			err := s.fakeClient.Get(ctx, cronJobKey("node-b"), &batchv1.CronJob{})
+			assert.True(s.T(), errors.IsNotFound(err))
+
+			jobs := &batchv1.JobList{}
+			require.NoError(s.T(), s.fakeClient.List(ctx, jobs, client.HasLabels{NodeRemovalHookLabel}))
+			assert.Empty(s.T(), jobs.Items)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestNodeEvent_Remove_DeferCronJobDeletion() {
+	cronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+	cronSet.Spec.NodeRemovalGracePeriod = &metav1.Duration{Duration: 10 * time.Minute}
+	require.NoError(s.T(), s.fakeClient.Update(ctx, cronSet))
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+
+	key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+	relabelNode := func(value string) reconcile.Result {
+		node := &corev1.Node{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: s.node.Name}, node))
+		node.Labels = map[string]string{"foo": value}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, node))
+		result, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+		return result
+	}
+
+	s.Run("When the node leaves the CronSet", func() {
+		s.drainEvents()
+		result := relabelNode("bar1")
+
+		s.Run("Should suspend the CronJob until the end of the grace period", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			assert.Equal(s.T(), ptr.To(true), cronJob.Spec.Suspend)
+			deletionTime, pending := pendingDeletionTime(cronJob)
+			require.True(s.T(), pending)
+			assert.WithinDuration(s.T(), time.Now().Add(10*time.Minute), deletionTime, 2*time.Second)
+			assert.Greater(s.T(), result.RequeueAfter, 9*time.Minute)
+			assert.LessOrEqual(s.T(), result.RequeueAfter, 10*time.Minute)
+			assert.Contains(s.T(), strings.Join(s.drainEvents(), "\n"), "Normal "+EventReasonPendingDeletion)
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			require.Len(s.T(), updatedCronSet.Status.Nodes, 1)
+			require.NotNil(s.T(), updatedCronSet.Status.Nodes[0].PendingDeletionTime)
+			assert.True(s.T(), deletionTime.Equal(updatedCronSet.Status.Nodes[0].PendingDeletionTime.Time))
+		})
+
+		s.Run("Should not count the CronJob pending deletion against the availability", func() {
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.CurrentNumberScheduled)
+			assert.Equal(s.T(), int32(0), updatedCronSet.Status.DesiredNumberScheduled)
+			available := meta.FindStatusCondition(updatedCronSet.Status.Conditions, batchv1alpha1.CronSetAvailable)
+			require.NotNil(s.T(), available)
+			assert.Equal(s.T(), metav1.ConditionTrue, available.Status)
+			assert.Equal(s.T(), "0/0 CronJob(s) are scheduled", available.Message)
+		})
+	})
+
+	s.Run("When the node comes back within the grace period", func() {
+		relabelNode("bar")
+
+		s.Run("Should resume the CronJob", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			assert.Nil(s.T(), cronJob.Spec.Suspend)
+			assert.NotContains(s.T(), cronJob.Annotations, PendingDeletionAnnotation)
+		})
+	})
+
+	s.Run("When the grace period of a departed node ends", func() {
+		relabelNode("bar1")
+		cronJob := &batchv1.CronJob{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+		cronJob.Annotations[PendingDeletionAnnotation] = time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+		require.NoError(s.T(), s.fakeClient.Update(ctx, cronJob))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should delete the CronJob", func() {
+			err := s.fakeClient.Get(ctx, key, &batchv1.CronJob{})
+			assert.True(s.T(), errors.IsNotFound(err))
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_DeleteWithOrphanPolicy_AdoptCronJobs() {
+	cronSet := &batchv1alpha1.CronSet{}
+	require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+	cronSet.Spec.DeletionPolicy = batchv1alpha1.DeletionPolicyOrphan
+	require.NoError(s.T(), s.fakeClient.Update(ctx, cronSet))
+	_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+	require.NoError(s.T(), err)
+	key := types.NamespacedName{Name: generateCronJobName(CronSetName, s.node.Name), Namespace: CronSetNamespace}
+
+	s.Run("When the deletion policy is Orphan", func() {
+		s.Run("Should add the finalizer", func() {
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+			assert.Contains(s.T(), cronSet.Finalizers, OrphanFinalizer)
+		})
+	})
+
+	s.Run("When the CronSet is deleted", func() {
+		require.NoError(s.T(), s.fakeClient.Delete(ctx, cronSet))
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should orphan the CronJobs", func() {
+			assert.True(s.T(), errors.IsNotFound(s.fakeClient.Get(ctx, cronSetKey, &batchv1alpha1.CronSet{})))
+
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			assert.Empty(s.T(), cronJob.OwnerReferences)
+			assert.NotContains(s.T(), cronJob.Labels, OwnerLabel)
+			assert.NotContains(s.T(), cronJob.Labels, OwnerUIDLabel)
+			assert.Equal(s.T(), CronSetName, cronJob.Labels[OrphanedFromLabel])
+		})
+	})
+
+	s.Run("When the CronSet is recreated", func() {
+		// The garbage collector deletes the revisions of the deleted CronSet.
+		require.NoError(s.T(), s.fakeClient.DeleteAllOf(ctx, &appsv1.ControllerRevision{}, client.InNamespace(CronSetNamespace)))
+		recreatedCronSet := s.cronSet.DeepCopy()
+		recreatedCronSet.ResourceVersion = ""
+		recreatedCronSet.UID = "recreated-uid"
+		require.NoError(s.T(), s.fakeClient.Create(ctx, recreatedCronSet))
+		s.drainEvents()
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should adopt the orphaned CronJobs", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, key, cronJob))
+			assert.True(s.T(), metav1.IsControlledBy(cronJob, recreatedCronSet))
+			assert.Equal(s.T(), "recreated-uid", cronJob.Labels[OwnerUIDLabel])
+			assert.NotContains(s.T(), cronJob.Labels, OrphanedFromLabel)
+			assert.Contains(s.T(), strings.Join(s.drainEvents(), "\n"), "Normal "+EventReasonAdopted)
+
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			assert.Equal(s.T(), int32(1), updatedCronSet.Status.CurrentNumberScheduled)
+			assert.NotContains(s.T(), updatedCronSet.Finalizers, OrphanFinalizer)
+		})
+	})
+}
+
+func (s *CronSetSuite) TestCronSetEvent_Adoption_AdoptPreExistingCronJobs() {
+	require.NoError(s.T(), s.fakeClient.Create(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{"foo": "bar", corev1.LabelHostname: "node-b-host"}},
+	}))
+	legacyCronJob := func(name string, uid types.UID, podSpec corev1.PodSpec) *batchv1.CronJob {
+		podSpec.Containers = []corev1.Container{{Name: "legacy", Image: "legacy"}}
+		podSpec.RestartPolicy = corev1.RestartPolicyOnFailure
+		return &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: CronSetNamespace, UID: uid, Labels: map[string]string{"app": "legacy"}},
+			Spec: batchv1.CronJobSpec{
+				Schedule:    "5 * * * *",
+				JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}}},
+			},
+		}
+	}
+	sameName := generateCronJobName(CronSetName, s.node.Name)
+	require.NoError(s.T(), s.fakeClient.Create(ctx, legacyCronJob(sameName, "same-name-uid", corev1.PodSpec{NodeName: s.node.Name})))
+	require.NoError(s.T(), s.fakeClient.Create(ctx, legacyCronJob("legacy-node-b", "legacy-node-b-uid",
+		corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelHostname: "node-b-host"}})))
+	require.NoError(s.T(), s.fakeClient.Create(ctx, legacyCronJob("legacy-gone", "legacy-gone-uid", corev1.PodSpec{NodeName: "gone"})))
+	require.NoError(s.T(), s.fakeClient.Create(ctx, &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "legacy-node-b-1",
+			Namespace: CronSetNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch/v1", Kind: "CronJob", Name: "legacy-node-b", UID: "legacy-node-b-uid", Controller: &trueVal,
+			}},
+		},
+	}))
+
+	s.Run("When the adoption selector is empty", func() {
+		cronSet := &batchv1alpha1.CronSet{Spec: batchv1alpha1.CronSetSpec{Adoption: &batchv1alpha1.CronJobAdoption{}}}
+
+		s.Run("Should reject it", func() {
+			_, err := adoptionSelector(cronSet)
+			assert.Error(s.T(), err)
+		})
+	})
+
+	s.Run("When the CronSet adopts the CronJobs", func() {
+		cronSet := &batchv1alpha1.CronSet{}
+		require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, cronSet))
+		cronSet.Spec.Adoption = &batchv1alpha1.CronJobAdoption{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "legacy"}},
+		}
+		require.NoError(s.T(), s.fakeClient.Update(ctx, cronSet))
+		s.drainEvents()
+		s.reconciler.Client = interceptor.NewClient(s.fakeClient.(client.WithWatch), interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if _, ok := obj.(*batchv1.Job); ok {
+					return errors.NewConflict(batchv1.Resource("jobs"), obj.GetName(), fmt.Errorf("conflict"))
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		})
+		_, err := s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.Error(s.T(), err)
+
+		s.Run("Should suspend the CronJob to replace until its jobs are handed over", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: "legacy-node-b", Namespace: CronSetNamespace}, cronJob))
+			assert.Equal(s.T(), ptr.To(true), cronJob.Spec.Suspend)
+		})
+
+		s.reconciler.Client = s.fakeClient
+		_, err = s.reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: cronSetKey})
+		require.NoError(s.T(), err)
+
+		s.Run("Should adopt the CronJob with the canonical name in place", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: sameName, Namespace: CronSetNamespace}, cronJob))
+			assert.Equal(s.T(), types.UID("same-name-uid"), cronJob.UID)
+			assert.True(s.T(), metav1.IsControlledBy(cronJob, cronSet))
+			assert.Equal(s.T(), string(cronSet.UID), cronJob.Labels[OwnerUIDLabel])
+			assert.Equal(s.T(), sameName, cronJob.Annotations[AdoptedFromAnnotation])
+			assert.Equal(s.T(), "1 * * * *", cronJob.Spec.Schedule)
+		})
+
+		s.Run("Should replace the CronJob with another name and hand over its jobs", func() {
+			err := s.fakeClient.Get(ctx, types.NamespacedName{Name: "legacy-node-b", Namespace: CronSetNamespace}, &batchv1.CronJob{})
+			assert.True(s.T(), errors.IsNotFound(err))
+
+			cronJobName := generateCronJobName(CronSetName, "node-b")
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: cronJobName, Namespace: CronSetNamespace}, cronJob))
+			assert.Equal(s.T(), "legacy-node-b", cronJob.Annotations[AdoptedFromAnnotation])
+
+			job := &batchv1.Job{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: "legacy-node-b-1", Namespace: CronSetNamespace}, job))
+			assert.Equal(s.T(), cronJobName, metav1.GetControllerOf(job).Name)
+		})
+
+		s.Run("Should leave the CronJobs of other nodes alone", func() {
+			cronJob := &batchv1.CronJob{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, types.NamespacedName{Name: "legacy-gone", Namespace: CronSetNamespace}, cronJob))
+			assert.Empty(s.T(), cronJob.OwnerReferences)
+		})
+
+		s.Run("Should report the adoptions", func() {
+			updatedCronSet := &batchv1alpha1.CronSet{}
+			require.NoError(s.T(), s.fakeClient.Get(ctx, cronSetKey, updatedCronSet))
+			adoptedFrom := make(map[string]string)
+			for _, nodeStatus := range updatedCronSet.Status.Nodes {
+				adoptedFrom[nodeStatus.NodeName] = nodeStatus.AdoptedFrom
+			}
+			assert.Equal(s.T(), map[string]string{s.node.Name: sameName, "node-b": "legacy-node-b"}, adoptedFrom)
+			assert.Equal(s.T(), int32(2), updatedCronSet.Status.CurrentNumberScheduled)
+
+			events := strings.Join(s.drainEvents(), "\n")
+			assert.Contains(s.T(), events, "Normal "+EventReasonAdopted+" Adopted CronJob "+sameName)
+			assert.Contains(s.T(), events, "Normal "+EventReasonAdopted+" Replaced CronJob legacy-node-b")
+		})
+	})
+}
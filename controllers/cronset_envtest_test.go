@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("CronSet", func() {
+	It("keeps the metadata of its templates through the API server", func() {
+		podTemplate := corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers:    []corev1.Container{{Name: "test-container", Image: "test-image"}},
+				RestartPolicy: corev1.RestartPolicyOnFailure,
+				NodeSelector:  map[string]string{"envtest": "metadata"},
+			},
+		}
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "envtest-metadata-node",
+			Labels: map[string]string{"envtest": "metadata"},
+		}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		cronSet := &batchv1alpha1.CronSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "envtest-metadata", Namespace: "default"},
+			Spec: batchv1alpha1.CronSetSpec{
+				CronJobTemplate: batchv1alpha1.CronJobTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      map[string]string{"team": "infra"},
+						Annotations: map[string]string{"owner": "infra@example.com"},
+					},
+					Spec: batchv1.CronJobSpec{
+						Schedule: "1 * * * *",
+						JobTemplate: batchv1.JobTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"job": "cron"}},
+							Spec:       batchv1.JobSpec{Template: podTemplate},
+						},
+					},
+				},
+				OnNodeRemoval: &batchv1alpha1.NodeRemovalHook{
+					JobTemplate: batchv1.JobTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"job": "hook"}},
+						Spec:       batchv1.JobSpec{Template: podTemplate},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, cronSet)).To(Succeed())
+
+		fetched := &batchv1alpha1.CronSet{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cronSet), fetched)).To(Succeed())
+		Expect(fetched.Spec.CronJobTemplate.Labels).To(HaveKeyWithValue("team", "infra"))
+		Expect(fetched.Spec.CronJobTemplate.Annotations).To(HaveKeyWithValue("owner", "infra@example.com"))
+		Expect(fetched.Spec.CronJobTemplate.Spec.JobTemplate.Labels).To(HaveKeyWithValue("job", "cron"))
+		Expect(fetched.Spec.OnNodeRemoval.JobTemplate.Labels).To(HaveKeyWithValue("job", "hook"))
+
+		Eventually(func(g Gomega) {
+			cronJobList := &batchv1.CronJobList{}
+			g.Expect(k8sClient.List(ctx, cronJobList,
+				client.InNamespace(cronSet.Namespace),
+				client.MatchingLabels{OwnerUIDLabel: string(fetched.UID)},
+			)).To(Succeed())
+			g.Expect(cronJobList.Items).To(HaveLen(1))
+			g.Expect(cronJobList.Items[0].Labels).To(HaveKeyWithValue("team", "infra"))
+			g.Expect(cronJobList.Items[0].Annotations).To(HaveKeyWithValue("owner", "infra@example.com"))
+		}, 10*time.Second, 250*time.Millisecond).Should(Succeed())
+	})
+})
@@ -0,0 +1,96 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the events emitted by the controller.
+const (
+	EventReasonSuccessfulCreate         = "SuccessfulCreate"
+	EventReasonSuccessfulUpdate         = "SuccessfulUpdate"
+	EventReasonSuccessfulDelete         = "SuccessfulDelete"
+	EventReasonFailedApply              = "FailedApply"
+	EventReasonDeletedInvalidCronJob    = "DeletedInvalidCronJob"
+	EventReasonRolledBack               = "RolledBack"
+	EventReasonRollbackRevisionNotFound = "RollbackRevisionNotFound"
+	EventReasonCanaryPromoted           = "CanaryPromoted"
+	EventReasonCanaryFailed             = "CanaryFailed"
+	EventReasonTerminationDelayExceeded = "TerminationDelayExceeded"
+	EventReasonNodeRemovalHookStarted   = "NodeRemovalHookStarted"
+	EventReasonNodeRemovalHookFailed    = "NodeRemovalHookFailed"
+	EventReasonPendingDeletion          = "PendingDeletion"
+	EventReasonOrphaned                 = "Orphaned"
+	EventReasonAdopted                  = "Adopted"
+)
+
+// maxEventsPerReason is the number of events of the same type and reason emitted for an object in
+// a single reconcile. Beyond it, a single summary event is emitted instead.
+const maxEventsPerReason = 10
+
+// maxSummaryExamples is the number of messages quoted in a summary event.
+const maxSummaryExamples = 3
+
+type eventKey struct {
+	eventType string
+	reason    string
+}
+
+// eventAggregator collects the events of a reconcile and emits them at once, so that a CronSet
+// spanning thousands of nodes doesn't flood the events API.
+type eventAggregator struct {
+	recorder record.EventRecorder
+	keys     []eventKey
+	messages map[eventKey][]string
+}
+
+func newEventAggregator(recorder record.EventRecorder) *eventAggregator {
+	return &eventAggregator{
+		recorder: recorder,
+		messages: make(map[eventKey][]string),
+	}
+}
+
+func (a *eventAggregator) add(eventType, reason, messageFmt string, args ...interface{}) {
+	key := eventKey{eventType: eventType, reason: reason}
+	if _, ok := a.messages[key]; !ok {
+		a.keys = append(a.keys, key)
+	}
+	a.messages[key] = append(a.messages[key], fmt.Sprintf(messageFmt, args...))
+}
+
+// flush emits the collected events on the object.
+func (a *eventAggregator) flush(object runtime.Object) {
+	for _, key := range a.keys {
+		messages := a.messages[key]
+		if len(messages) <= maxEventsPerReason {
+			for _, message := range messages {
+				a.recorder.Event(object, key.eventType, key.reason, message)
+			}
+			continue
+		}
+		a.recorder.Eventf(object, key.eventType, key.reason, "%d similar events, e.g. %s",
+			len(messages), strings.Join(messages[:maxSummaryExamples], "; "))
+	}
+	a.keys = nil
+	a.messages = make(map[eventKey][]string)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// defaultRevisionHistoryLimit is used when the CronSet doesn't set spec.revisionHistoryLimit.
+const defaultRevisionHistoryLimit = 10
+
+// revisionName returns the name of the ControllerRevision of a template hash.
+func revisionName(cronSetName string, templateHash string) string {
+	return truncateWithHash(cronSetName+"-"+templateHash, validation.DNS1123SubdomainMaxLength)
+}
+
+// listRevisions lists the ControllerRevisions of the CronSet.
+func (r *CronSetReconciler) listRevisions(ctx context.Context, cronSet *batchv1alpha1.CronSet) ([]appsv1.ControllerRevision, error) {
+	revisionList := &appsv1.ControllerRevisionList{}
+	if err := r.List(ctx, revisionList,
+		client.InNamespace(cronSet.Namespace),
+		client.MatchingLabels{OwnerUIDLabel: string(cronSet.UID)},
+	); err != nil {
+		return nil, err
+	}
+
+	var revisions []appsv1.ControllerRevision
+	for _, revision := range revisionList.Items {
+		if metav1.IsControlledBy(&revision, cronSet) {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+// listRevisionCronSets returns a copy of the CronSet with the template of each of its revisions,
+// keyed by template hash.
+func (r *CronSetReconciler) listRevisionCronSets(ctx context.Context, cronSet *batchv1alpha1.CronSet) (map[string]*batchv1alpha1.CronSet, error) {
+	revisions, err := r.listRevisions(ctx, cronSet)
+	if err != nil {
+		return nil, err
+	}
+	cronSets := make(map[string]*batchv1alpha1.CronSet, len(revisions))
+	for _, revision := range revisions {
+		var spec templateSpec
+		if err := json.Unmarshal(revision.Data.Raw, &spec); err != nil {
+			return nil, fmt.Errorf("unable to decode revision %s: %w", revision.Name, err)
+		}
+		cronSets[revision.Labels[TemplateHashLabel]] = withTemplate(cronSet, &spec)
+	}
+	return cronSets, nil
+}
+
+// syncRevisions snapshots the current template of the CronSet into a ControllerRevision with the
+// highest revision number, and prunes the old revisions beyond the history limit.
+// It returns the name of the current revision.
+func (r *CronSetReconciler) syncRevisions(ctx context.Context, cronSet *batchv1alpha1.CronSet, cronJobs []batchv1.CronJob) (string, error) {
+	revisions, err := r.listRevisions(ctx, cronSet)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(newTemplateSpec(cronSet))
+	if err != nil {
+		return "", err
+	}
+	templateHash := computeTemplateHash(cronSet)
+	var current *appsv1.ControllerRevision
+	maxRevision := int64(0)
+	for i := range revisions {
+		maxRevision = max(maxRevision, revisions[i].Revision)
+		if revisions[i].Labels[TemplateHashLabel] == templateHash {
+			current = &revisions[i]
+		}
+	}
+
+	if current != nil && !revisionMatches(current, data) {
+		// Another template has the same hash: the collision count changes the hash of the template,
+		// which gets a revision of its own on the next attempt.
+		collisionCount := ptr.Deref(cronSet.Status.CollisionCount, 0) + 1
+		cronSet.Status.CollisionCount = &collisionCount
+		if err := r.Status().Update(ctx, cronSet); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("template hash %s collides with revision %s", templateHash, current.Name)
+	}
+
+	switch {
+	case current == nil:
+		revision := &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      revisionName(cronSet.Name, templateHash),
+				Namespace: cronSet.Namespace,
+				Labels: map[string]string{
+					OwnerLabel:        truncateLabelValue(cronSet.Name),
+					OwnerUIDLabel:     string(cronSet.UID),
+					TemplateHashLabel: templateHash,
+				},
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: maxRevision + 1,
+		}
+		if err := controllerutil.SetControllerReference(cronSet, revision, r.Scheme); err != nil {
+			return "", err
+		}
+		if err := r.Create(ctx, revision); err != nil {
+			return "", err
+		}
+		r.Log.Info("Create ControllerRevision", "cronset", cronSet.Name, "revision", revision.Name, "number", revision.Revision)
+		revisions = append(revisions, *revision)
+		current = revision
+	case current.Revision < maxRevision:
+		// A previous template is back, e.g. after a rollback: it becomes the latest revision.
+		current.Revision = maxRevision + 1
+		if err := r.Update(ctx, current); err != nil {
+			return "", err
+		}
+	}
+
+	return current.Name, r.pruneRevisions(ctx, cronSet, revisions, current.Name, cronJobs)
+}
+
+// pruneRevisions deletes the oldest revisions beyond the history limit of the CronSet. The current
+// revision and the revisions still used by a CronJob are kept and don't count towards the limit.
+func (r *CronSetReconciler) pruneRevisions(ctx context.Context, cronSet *batchv1alpha1.CronSet, revisions []appsv1.ControllerRevision,
+	currentRevisionName string, cronJobs []batchv1.CronJob) error {
+	usedHashes := make(map[string]bool)
+	for _, cronJob := range cronJobs {
+		usedHashes[cronJob.Labels[TemplateHashLabel]] = true
+	}
+
+	var oldRevisions []appsv1.ControllerRevision
+	for _, revision := range revisions {
+		if revision.Name != currentRevisionName && !usedHashes[revision.Labels[TemplateHashLabel]] {
+			oldRevisions = append(oldRevisions, revision)
+		}
+	}
+	limit := int(ptr.Deref(cronSet.Spec.RevisionHistoryLimit, defaultRevisionHistoryLimit))
+	if len(oldRevisions) <= limit {
+		return nil
+	}
+
+	sort.Slice(oldRevisions, func(i, j int) bool {
+		return oldRevisions[i].Revision < oldRevisions[j].Revision
+	})
+	for _, revision := range oldRevisions[:len(oldRevisions)-limit] {
+		if err := r.Delete(ctx, &revision, client.Preconditions{UID: &revision.UID}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.Log.Info("Delete ControllerRevision", "cronset", cronSet.Name, "revision", revision.Name, "number", revision.Revision)
+	}
+	return nil
+}
+
+// rollback replaces the template of the CronSet with the one of the revision named by
+// spec.rollbackTo, and clears spec.rollbackTo. An unknown revision is reported with an event.
+func (r *CronSetReconciler) rollback(ctx context.Context, cronSet *batchv1alpha1.CronSet) error {
+	name := cronSet.Spec.RollbackTo.RevisionName
+	cronSet.Spec.RollbackTo = nil
+
+	spec, err := r.getRevisionTemplate(ctx, cronSet, name)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err != nil {
+		if err := r.Update(ctx, cronSet); err != nil {
+			return err
+		}
+		r.Recorder.Eventf(cronSet, corev1.EventTypeWarning, EventReasonRollbackRevisionNotFound, "Unable to find revision %s of the CronSet", name)
+		return nil
+	}
+
+	cronSet.Spec.CronJobTemplate = spec.CronJobTemplate
+	cronSet.Spec.DefaultTolerations = spec.DefaultTolerations
+	cronSet.Spec.Overrides = spec.Overrides
+	cronSet.Spec.Stagger = spec.Stagger
+	if err := r.Update(ctx, cronSet); err != nil {
+		return err
+	}
+	r.Log.Info("Roll back", "cronset", cronSet.Name, "revision", name)
+	r.Recorder.Eventf(cronSet, corev1.EventTypeNormal, EventReasonRolledBack, "Rolled back to revision %s", name)
+	return nil
+}
+
+// revisionMatches reports whether the revision holds the given template data. Both sides are
+// encoded the same way, so that fields dropped by the decoding don't make them differ.
+func revisionMatches(revision *appsv1.ControllerRevision, data []byte) bool {
+	var spec templateSpec
+	if err := json.Unmarshal(revision.Data.Raw, &spec); err != nil {
+		return false
+	}
+	revisionData, err := json.Marshal(spec)
+	return err == nil && bytes.Equal(revisionData, data)
+}
+
+// getRevisionTemplate returns the template stored in the named revision of the CronSet. A revision
+// which is not controlled by the CronSet is reported as not found.
+func (r *CronSetReconciler) getRevisionTemplate(ctx context.Context, cronSet *batchv1alpha1.CronSet, name string) (*templateSpec, error) {
+	revision := &appsv1.ControllerRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: cronSet.Namespace}, revision); err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(revision, cronSet) {
+		return nil, errors.NewNotFound(appsv1.Resource("controllerrevisions"), name)
+	}
+
+	var spec templateSpec
+	if err := json.Unmarshal(revision.Data.Raw, &spec); err != nil {
+		return nil, fmt.Errorf("unable to decode revision %s: %w", name, err)
+	}
+	return &spec, nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "cronset"
+
+var (
+	desiredCronJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "desired_cronjobs",
+		Help:      "Number of nodes that should run a CronJob of the CronSet.",
+	}, []string{"namespace", "cronset"})
+	currentCronJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "current_cronjobs",
+		Help:      "Number of CronJobs owned by the CronSet.",
+	}, []string{"namespace", "cronset"})
+	misscheduledCronJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "misscheduled_cronjobs",
+		Help:      "Number of nodes whose CronJob couldn't be applied in the last reconcile of the CronSet.",
+	}, []string{"namespace", "cronset"})
+	applyFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "apply_failures_total",
+		Help:      "Total number of failures to apply the CronJob of a node, by node and reason.",
+	}, []string{"namespace", "cronset", "node", "reason"})
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of the reconciles of the CronSet.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"namespace", "cronset"})
+	nodeEventEnqueuesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_event_enqueues_total",
+		Help:      "Total number of reconciles of the CronSet enqueued by node events.",
+	}, []string{"namespace", "cronset"})
+	lastSuccessfulRuns = newLastSuccessfulRunCollector()
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		desiredCronJobs,
+		currentCronJobs,
+		misscheduledCronJobs,
+		applyFailuresTotal,
+		reconcileDuration,
+		nodeEventEnqueuesTotal,
+		lastSuccessfulRuns,
+	)
+}
+
+// recordCronSetMetrics updates the gauges of the CronSet after a reconcile.
+func recordCronSetMetrics(key types.NamespacedName, status CronSetStatus, ownedCronJobs []batchv1.CronJob) {
+	desiredCronJobs.WithLabelValues(key.Namespace, key.Name).Set(float64(status.DesiredScheduledJobCount))
+	currentCronJobs.WithLabelValues(key.Namespace, key.Name).Set(float64(status.CurrentDependentCronJobCount))
+	misscheduledCronJobs.WithLabelValues(key.Namespace, key.Name).Set(float64(status.MisScheduledJobCount))
+	lastSuccessfulRuns.set(key, ownedCronJobs)
+}
+
+func observeReconcileDuration(key types.NamespacedName, start time.Time) {
+	reconcileDuration.WithLabelValues(key.Namespace, key.Name).Observe(time.Since(start).Seconds())
+}
+
+// forgetCronSetMetrics drops every series of a deleted CronSet.
+func forgetCronSetMetrics(key types.NamespacedName) {
+	labels := prometheus.Labels{"namespace": key.Namespace, "cronset": key.Name}
+	desiredCronJobs.DeletePartialMatch(labels)
+	currentCronJobs.DeletePartialMatch(labels)
+	misscheduledCronJobs.DeletePartialMatch(labels)
+	applyFailuresTotal.DeletePartialMatch(labels)
+	reconcileDuration.DeletePartialMatch(labels)
+	nodeEventEnqueuesTotal.DeletePartialMatch(labels)
+	lastSuccessfulRuns.forget(key)
+}
+
+var lastSuccessfulRunAgeDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metricsNamespace, "", "last_successful_run_age_seconds"),
+	"Time since the last successful run of the CronJob of the CronSet on the node.",
+	[]string{"namespace", "cronset", "node"}, nil,
+)
+
+// lastSuccessfulRunCollector computes the age of the last successful runs at scrape time, so that it
+// keeps growing between two reconciles of the CronSet.
+type lastSuccessfulRunCollector struct {
+	mu sync.Mutex
+	// lastSuccessfulTimes holds the last successful time of every node, by CronSet.
+	lastSuccessfulTimes map[types.NamespacedName]map[string]time.Time
+	now                 func() time.Time
+}
+
+func newLastSuccessfulRunCollector() *lastSuccessfulRunCollector {
+	return &lastSuccessfulRunCollector{
+		lastSuccessfulTimes: make(map[types.NamespacedName]map[string]time.Time),
+		now:                 time.Now,
+	}
+}
+
+// set replaces the last successful times of the CronSet with the ones of its CronJobs.
+// CronJobs which never succeeded are not reported.
+func (c *lastSuccessfulRunCollector) set(key types.NamespacedName, cronJobs []batchv1.CronJob) {
+	lastSuccessfulTimes := make(map[string]time.Time)
+	for _, cronJob := range cronJobs {
+		if cronJob.Status.LastSuccessfulTime == nil {
+			continue
+		}
+		lastSuccessfulTimes[cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName] = cronJob.Status.LastSuccessfulTime.Time
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastSuccessfulTimes[key] = lastSuccessfulTimes
+}
+
+func (c *lastSuccessfulRunCollector) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.lastSuccessfulTimes, key)
+}
+
+func (c *lastSuccessfulRunCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lastSuccessfulRunAgeDesc
+}
+
+func (c *lastSuccessfulRunCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for key, lastSuccessfulTimes := range c.lastSuccessfulTimes {
+		for nodeName, lastSuccessfulTime := range lastSuccessfulTimes {
+			ch <- prometheus.MustNewConstMetric(lastSuccessfulRunAgeDesc, prometheus.GaugeValue,
+				now.Sub(lastSuccessfulTime).Seconds(), key.Namespace, key.Name, nodeName)
+		}
+	}
+}
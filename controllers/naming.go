@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// cronJobMaxNameLength is the maximum length of a CronJob name. The CronJob controller appends
+// an 11 character suffix to it when naming Jobs, which must fit into 63 characters.
+const cronJobMaxNameLength = 52
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+func getNodeIdentifier(node *corev1.Node) string {
+	identificationKey := os.Getenv(NodeIdentificationKey)
+	if annotationValue, ok := node.Annotations[identificationKey]; ok {
+		return annotationValue
+	}
+	return node.Name
+}
+
+// generateCronJobName joins the CronSet name and the node identifier with "-". If the result doesn't
+// fit into a CronJob name, it is truncated and suffixed with a hash of the full name, so that
+// different node identifiers still lead to different names.
+func generateCronJobName(cronSetName string, nodeIdentifier string) string {
+	return truncateWithHash(strings.Join([]string{cronSetName, sanitizeNodeIdentifier(nodeIdentifier)}, "-"), cronJobMaxNameLength)
+}
+
+// sanitizeNodeIdentifier turns the node identifier into a DNS subdomain: invalid characters are
+// replaced with "-", and every dot-separated part is trimmed so that it starts and ends with an
+// alphanumeric character. An identifier without any valid character is replaced with its hash.
+func sanitizeNodeIdentifier(nodeIdentifier string) string {
+	parts := strings.Split(invalidNameChars.ReplaceAllString(strings.ToLower(nodeIdentifier), "-"), ".")
+	sanitizedParts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.Trim(part, "-"); part != "" {
+			sanitizedParts = append(sanitizedParts, part)
+		}
+	}
+	if len(sanitizedParts) == 0 {
+		return shortHash(nodeIdentifier)
+	}
+	return strings.Join(sanitizedParts, ".")
+}
+
+// assignCronJobNames returns the CronJob name of every node, keyed by node name.
+// Nodes sharing the same identifier (e.g. the same NodeIdentificationKey annotation value) would get the
+// same name; all of them get the hash of their node name appended instead, so that the name of a node
+// doesn't depend on which other nodes share its identifier.
+func assignCronJobNames(cronSetName string, nodes []corev1.Node) map[string]string {
+	nodesByName := make(map[string][]*corev1.Node, len(nodes))
+	for i := range nodes {
+		name := generateCronJobName(cronSetName, getNodeIdentifier(&nodes[i]))
+		nodesByName[name] = append(nodesByName[name], &nodes[i])
+	}
+
+	names := make(map[string]string, len(nodes))
+	for name, collidingNodes := range nodesByName {
+		if len(collidingNodes) == 1 {
+			names[collidingNodes[0].Name] = name
+			continue
+		}
+		for _, node := range collidingNodes {
+			names[node.Name] = generateCronJobName(cronSetName, getNodeIdentifier(node)+"-"+shortHash(node.Name))
+		}
+	}
+	return names
+}
+
+// truncateLabelValue makes the value fit into a label value.
+func truncateLabelValue(value string) string {
+	return truncateWithHash(value, validation.LabelValueMaxLength)
+}
+
+// truncateWithHash returns the value unchanged if it is not longer than maxLength. Otherwise it is
+// truncated and suffixed with a hash of the full value, so that the result is at most maxLength long.
+// Dots and dashes at the cut are dropped, so that a valid name stays valid.
+func truncateWithHash(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	suffix := "-" + shortHash(value)
+	return strings.TrimRight(value[:maxLength-len(suffix)], ".-") + suffix
+}
+
+// shortHash returns a stable 8 character hex hash of the value.
+func shortHash(value string) string {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(value))
+	return fmt.Sprintf("%08x", hasher.Sum32())
+}
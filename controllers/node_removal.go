@@ -0,0 +1,201 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// NodeRemovalHookLabel marks the node removal hook Jobs. Its value is the name of the CronJob
+	// of the departing node.
+	NodeRemovalHookLabel = "grasse.io/node-removal-hook"
+	// CronJobUIDAnnotation records the UID of the CronJob a node removal hook Job was run for.
+	CronJobUIDAnnotation = "grasse.io/cronjob-uid"
+	// PendingDeletionAnnotation records when the suspended CronJob of a departed node is deleted.
+	PendingDeletionAnnotation = "grasse.io/pending-deletion-at"
+)
+
+// defaultNodeRemovalHookTimeout is used when the node removal hook doesn't set a timeout.
+const defaultNodeRemovalHookTimeout = 10 * time.Minute
+
+// nodeRemovalHookJobName returns the name of the node removal hook Job of the CronJob.
+func nodeRemovalHookJobName(cronJobName string) string {
+	return truncateWithHash(cronJobName+"-removal", validation.DNS1123LabelMaxLength)
+}
+
+func nodeRemovalHookTimeout(hook *batchv1alpha1.NodeRemovalHook) time.Duration {
+	if hook.Timeout == nil {
+		return defaultNodeRemovalHookTimeout
+	}
+	return hook.Timeout.Duration
+}
+
+// pendingDeletionTime returns when the CronJob of a departed node is deleted, if it is pending deletion.
+func pendingDeletionTime(cronJob *batchv1.CronJob) (time.Time, bool) {
+	deletionTime, err := time.Parse(time.RFC3339, cronJob.Annotations[PendingDeletionAnnotation])
+	return deletionTime, err == nil
+}
+
+// deferCronJobDeletion suspends the CronJob of a departed node until the end of the node removal
+// grace period, which is recorded on the CronJob so that it survives restarts of the controller.
+// It returns how long is left until the CronJob can be deleted.
+func (r *CronSetReconciler) deferCronJobDeletion(ctx context.Context, cronSet *batchv1alpha1.CronSet, cronJob *batchv1.CronJob,
+	events *eventAggregator, now time.Time) (time.Duration, error) {
+	deletionTime, pending := pendingDeletionTime(cronJob)
+	if !pending {
+		deletionTime = now.Add(cronSet.Spec.NodeRemovalGracePeriod.Duration)
+		patch := client.MergeFrom(cronJob.DeepCopy())
+		if cronJob.Annotations == nil {
+			cronJob.Annotations = make(map[string]string)
+		}
+		cronJob.Annotations[PendingDeletionAnnotation] = deletionTime.UTC().Format(time.RFC3339)
+		cronJob.Spec.Suspend = ptr.To(true)
+		if err := r.Patch(ctx, cronJob, patch); err != nil {
+			return 0, err
+		}
+		nodeName := cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName
+		events.add(corev1.EventTypeNormal, EventReasonPendingDeletion, "Suspended CronJob %s of departed node %s until %s",
+			cronJob.Name, nodeName, deletionTime.UTC().Format(time.RFC3339))
+		r.Log.Info("Suspend CronJob of departed node", "cronset", cronSet.Name, "cronjob", cronJob.Name, "node", nodeName)
+	}
+	return max(deletionTime.Sub(now), 0), nil
+}
+
+// runNodeRemovalHook launches the node removal hook Job on the node of the CronJob, which is about
+// to be deleted. It reports whether the CronJob can be deleted: the Job finished, the node is
+// gone, or the Job can't be created from the template.
+func (r *CronSetReconciler) runNodeRemovalHook(ctx context.Context, cronSet *batchv1alpha1.CronSet, cronJob *batchv1.CronJob, events *eventAggregator) (bool, error) {
+	hook := cronSet.Spec.OnNodeRemoval
+	nodeName := cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName
+	node := &corev1.Node{}
+	if err := r.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	job := &batchv1.Job{}
+	jobKey := types.NamespacedName{Name: nodeRemovalHookJobName(cronJob.Name), Namespace: cronSet.Namespace}
+	err := r.Get(ctx, jobKey, job)
+	switch {
+	case err == nil && job.Annotations[CronJobUIDAnnotation] != string(cronJob.UID):
+		// The Job was left behind by an earlier CronJob of the node, which departed before.
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		return false, nil
+	case err == nil:
+		if hasJobCondition(job, batchv1.JobFailed) {
+			events.add(corev1.EventTypeWarning, EventReasonNodeRemovalHookFailed, "Node removal hook Job %s failed on node %s", job.Name, nodeName)
+			return true, nil
+		}
+		return hasJobCondition(job, batchv1.JobComplete), nil
+	case !errors.IsNotFound(err):
+		return false, err
+	}
+
+	job = &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobKey.Name,
+			Namespace:   jobKey.Namespace,
+			Labels:      maps.Clone(hook.JobTemplate.Labels),
+			Annotations: maps.Clone(hook.JobTemplate.Annotations),
+		},
+		Spec: *hook.JobTemplate.Spec.DeepCopy(),
+	}
+	if job.Labels == nil {
+		job.Labels = make(map[string]string)
+	}
+	job.Labels[OwnerLabel] = truncateLabelValue(cronSet.Name)
+	job.Labels[OwnerUIDLabel] = string(cronSet.UID)
+	job.Labels[NodeLabel] = truncateLabelValue(nodeName)
+	job.Labels[NodeRemovalHookLabel] = cronJob.Name
+	if job.Annotations == nil {
+		job.Annotations = make(map[string]string)
+	}
+	job.Annotations[NodeNameAnnotation] = nodeName
+	job.Annotations[CronJobUIDAnnotation] = string(cronJob.UID)
+	job.Spec.Template.Spec.NodeName = nodeName
+	job.Spec.ActiveDeadlineSeconds = ptr.To(int64(nodeRemovalHookTimeout(hook).Seconds()))
+	if err := renderNodeTemplates(&job.Spec.Template.Spec, node); err != nil {
+		events.add(corev1.EventTypeWarning, EventReasonNodeRemovalHookFailed, "Unable to run the node removal hook on node %s: %v", nodeName, err)
+		return true, nil
+	}
+	if err := controllerutil.SetControllerReference(cronSet, job, r.Scheme); err != nil {
+		return false, err
+	}
+	if err := r.Create(ctx, job); err != nil {
+		if errors.IsInvalid(err) {
+			events.add(corev1.EventTypeWarning, EventReasonNodeRemovalHookFailed, "Unable to run the node removal hook on node %s: %v", nodeName, err)
+			return true, nil
+		}
+		return false, err
+	}
+	events.add(corev1.EventTypeNormal, EventReasonNodeRemovalHookStarted, "Started node removal hook Job %s on node %s", job.Name, nodeName)
+	r.Log.Info("Start node removal hook", "cronset", cronSet.Name, "job", job.Name, "node", nodeName)
+	return false, nil
+}
+
+// deleteNodeRemovalHookJobs deletes the node removal hook Jobs of the CronSet whose CronJob is gone,
+// along with their pods. Hook Jobs of a departed CronJob are kept until the CronJob is deleted, so
+// that a finished hook isn't run again.
+func (r *CronSetReconciler) deleteNodeRemovalHookJobs(ctx context.Context, cronSet *batchv1alpha1.CronSet) error {
+	cronJobs, err := r.listOwnedCronJobs(ctx, cronSet)
+	if err != nil {
+		return err
+	}
+	cronJobUIDs := make(map[string]types.UID, len(cronJobs))
+	for _, cronJob := range cronJobs {
+		cronJobUIDs[cronJob.Name] = cronJob.UID
+	}
+
+	jobList := &batchv1.JobList{}
+	if err := r.List(ctx, jobList,
+		client.InNamespace(cronSet.Namespace),
+		client.MatchingLabels{OwnerUIDLabel: string(cronSet.UID)},
+		client.HasLabels{NodeRemovalHookLabel},
+	); err != nil {
+		return err
+	}
+	for _, job := range jobList.Items {
+		cronJobUID, ok := cronJobUIDs[job.Labels[NodeRemovalHookLabel]]
+		if (ok && string(cronJobUID) == job.Annotations[CronJobUIDAnnotation]) || !metav1.IsControlledBy(&job, cronSet) {
+			continue
+		}
+		if err := r.Delete(ctx, &job, client.Preconditions{UID: &job.UID},
+			client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.Log.Info("Delete node removal hook", "cronset", cronSet.Name, "job", job.Name)
+	}
+	return nil
+}
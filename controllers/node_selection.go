@@ -0,0 +1,224 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+)
+
+// nodeSelectorForCronSet builds the label selector used to pick the nodes of a CronSet.
+// It is the conjunction of spec.selector and the nodeSelector of the pod template.
+func nodeSelectorForCronSet(cronSet *batchv1alpha1.CronSet) (labels.Selector, error) {
+	selector := labels.Everything()
+	if cronSet.Spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(cronSet.Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+
+	nodeSelector := cronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec.NodeSelector
+	if len(nodeSelector) != 0 {
+		requirements, _ := labels.SelectorFromValidatedSet(nodeSelector).Requirements()
+		selector = selector.Add(requirements...)
+	}
+
+	return selector, nil
+}
+
+// nodeMatchesCronSet reports whether the given node should run a CronJob of the CronSet.
+func nodeMatchesCronSet(cronSet *batchv1alpha1.CronSet, node *corev1.Node) (bool, error) {
+	selector, err := nodeSelectorForCronSet(cronSet)
+	if err != nil {
+		return false, err
+	}
+	if !selector.Matches(labels.Set(node.Labels)) {
+		return false, nil
+	}
+	return nodeMatchesAffinity(cronSet, node)
+}
+
+// nodeMatchesAffinity evaluates the nodeSelector and the required node affinity terms of the pod
+// template against the node, in the same way the DaemonSet controller does.
+// Unlike label selection, the affinity terms can also match fields such as metadata.name.
+func nodeMatchesAffinity(cronSet *batchv1alpha1.CronSet, node *corev1.Node) (bool, error) {
+	podSpec := cronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeSelector: podSpec.NodeSelector,
+			Affinity:     podSpec.Affinity,
+		},
+	}
+	return nodeaffinity.GetRequiredNodeAffinity(pod).Match(node)
+}
+
+// Node annotations listing the CronSets whose CronJob is suspended on, or excluded from, the node.
+// The value is "*" for every CronSet, or a comma separated list of CronSet names, optionally
+// qualified by their namespace as "<namespace>/<name>".
+const (
+	SuspendAnnotation = "cronset.grasse.io/suspend"
+	ExcludeAnnotation = "cronset.grasse.io/exclude"
+)
+
+// nodeAnnotationMatches reports whether the node annotation lists the CronSet.
+func nodeAnnotationMatches(cronSet *batchv1alpha1.CronSet, node *corev1.Node, annotation string) bool {
+	value, ok := node.Annotations[annotation]
+	if !ok {
+		return false
+	}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || item == cronSet.Name || item == cronSet.Namespace+"/"+cronSet.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultNotReadyGracePeriod is used when the node health policy doesn't set a grace period.
+const defaultNotReadyGracePeriod = 5 * time.Minute
+
+// nodeSelection is the outcome of evaluating the nodes against a CronSet.
+type nodeSelection struct {
+	// eligibleNodes are the nodes that get a CronJob.
+	eligibleNodes []corev1.Node
+	// suspendedNodes are the eligible nodes whose CronJob is suspended by the node health policy
+	// or by the suspend annotation of the node.
+	suspendedNodes map[string]bool
+	// excludedNodes are the selected nodes that don't get a CronJob.
+	excludedNodes []batchv1alpha1.ExcludedNode
+	// requeueAfter is set when a NotReady node reaches the end of its grace period later on.
+	requeueAfter time.Duration
+}
+
+// selectNodes evaluates the node affinity, opt-out annotations, taints and health of the nodes
+// against the CronSet. Nodes not satisfying the required node affinity are dropped without being
+// reported.
+func selectNodes(cronSet *batchv1alpha1.CronSet, nodes []corev1.Node, now time.Time) (*nodeSelection, error) {
+	selection := &nodeSelection{suspendedNodes: make(map[string]bool)}
+	policy := nodeHealthPolicyType(cronSet)
+	for _, node := range nodes {
+		matched, err := nodeMatchesAffinity(cronSet, &node)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		if nodeAnnotationMatches(cronSet, &node, ExcludeAnnotation) {
+			selection.excludedNodes = append(selection.excludedNodes, batchv1alpha1.ExcludedNode{
+				Name:   node.Name,
+				Reason: batchv1alpha1.ExcludedNodeReasonOptedOut,
+			})
+			continue
+		}
+		if taint, untolerated := findUntoleratedTaint(cronSet, &node); untolerated {
+			selection.excludedNodes = append(selection.excludedNodes, batchv1alpha1.ExcludedNode{
+				Name:   node.Name,
+				Reason: batchv1alpha1.ExcludedNodeReasonUntoleratedTaint,
+				Taint:  &taint,
+			})
+			continue
+		}
+
+		if policy != batchv1alpha1.NodeHealthPolicyKeep {
+			reason, unhealthy, recheckAfter := evaluateNodeHealth(&node, notReadyGracePeriod(cronSet), now)
+			if recheckAfter > 0 && (selection.requeueAfter == 0 || recheckAfter < selection.requeueAfter) {
+				selection.requeueAfter = recheckAfter
+			}
+			if unhealthy && policy == batchv1alpha1.NodeHealthPolicyRemove {
+				selection.excludedNodes = append(selection.excludedNodes, batchv1alpha1.ExcludedNode{
+					Name:   node.Name,
+					Reason: reason,
+				})
+				continue
+			}
+			if unhealthy {
+				selection.suspendedNodes[node.Name] = true
+			}
+		}
+
+		if nodeAnnotationMatches(cronSet, &node, SuspendAnnotation) {
+			selection.suspendedNodes[node.Name] = true
+		}
+		selection.eligibleNodes = append(selection.eligibleNodes, node)
+	}
+	return selection, nil
+}
+
+func nodeHealthPolicyType(cronSet *batchv1alpha1.CronSet) batchv1alpha1.NodeHealthPolicyType {
+	if cronSet.Spec.NodeHealthPolicy == nil || cronSet.Spec.NodeHealthPolicy.Type == "" {
+		return batchv1alpha1.NodeHealthPolicyKeep
+	}
+	return cronSet.Spec.NodeHealthPolicy.Type
+}
+
+func notReadyGracePeriod(cronSet *batchv1alpha1.CronSet) time.Duration {
+	if cronSet.Spec.NodeHealthPolicy == nil || cronSet.Spec.NodeHealthPolicy.NotReadyGracePeriod == nil {
+		return defaultNotReadyGracePeriod
+	}
+	return cronSet.Spec.NodeHealthPolicy.NotReadyGracePeriod.Duration
+}
+
+// evaluateNodeHealth reports whether the node is cordoned or has been NotReady for longer than the
+// grace period. For a node that is NotReady within the grace period, it returns how long is left.
+// A node without a Ready condition yet is considered healthy.
+func evaluateNodeHealth(node *corev1.Node, gracePeriod time.Duration, now time.Time) (batchv1alpha1.ExcludedNodeReason, bool, time.Duration) {
+	if node.Spec.Unschedulable {
+		return batchv1alpha1.ExcludedNodeReasonNodeCordoned, true, 0
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady || condition.Status == corev1.ConditionTrue {
+			continue
+		}
+		notReadyFor := now.Sub(condition.LastTransitionTime.Time)
+		if notReadyFor < gracePeriod {
+			return "", false, gracePeriod - notReadyFor
+		}
+		return batchv1alpha1.ExcludedNodeReasonNodeNotReady, true, 0
+	}
+	return "", false, 0
+}
+
+// nodeLifecycleTaints are managed by the node health policy when it is not Keep.
+var nodeLifecycleTaints = map[string]bool{
+	corev1.TaintNodeUnschedulable: true,
+	corev1.TaintNodeNotReady:      true,
+	corev1.TaintNodeUnreachable:   true,
+}
+
+// findUntoleratedTaint returns the first NoSchedule or NoExecute taint of the node which is not
+// tolerated by the pods of the CronSet. PreferNoSchedule taints never exclude a node, and the
+// node lifecycle taints don't either when the node health policy handles unhealthy nodes.
+func findUntoleratedTaint(cronSet *batchv1alpha1.CronSet, node *corev1.Node) (corev1.Taint, bool) {
+	healthManaged := nodeHealthPolicyType(cronSet) != batchv1alpha1.NodeHealthPolicyKeep
+	return corev1helpers.FindMatchingUntoleratedTaint(logr.Discard(), node.Spec.Taints, podTolerations(cronSet), func(taint *corev1.Taint) bool {
+		if healthManaged && nodeLifecycleTaints[taint.Key] {
+			return false
+		}
+		return taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute
+	}, false)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ReasonTemplateRenderFailed is the failure reason of a node whose pod template can't be rendered.
+const ReasonTemplateRenderFailed = "TemplateRenderFailed"
+
+// templateRenderError is a failure to render the node attributes into the pod template.
+type templateRenderError struct {
+	field string
+	err   error
+}
+
+func (e *templateRenderError) Error() string {
+	return fmt.Sprintf("unable to render %s: %v", e.field, e.err)
+}
+
+func (e *templateRenderError) Unwrap() error {
+	return e.err
+}
+
+// nodeTemplateData is the data available to the templates of the pod template.
+type nodeTemplateData struct {
+	Node nodeTemplateNode
+}
+
+type nodeTemplateNode struct {
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// renderNodeTemplates renders the Go templates found in the command, args and env values of the
+// containers, and in the host paths and mount paths of the volumes, with the attributes of the node.
+// Only the values containing "{{" are rendered.
+func renderNodeTemplates(podSpec *corev1.PodSpec, node *corev1.Node) error {
+	renderer := &nodeTemplateRenderer{data: nodeTemplateData{Node: nodeTemplateNode{
+		Name:        node.Name,
+		Labels:      node.Labels,
+		Annotations: node.Annotations,
+	}}}
+
+	for _, containers := range []struct {
+		field      string
+		containers []corev1.Container
+	}{{"initContainers", podSpec.InitContainers}, {"containers", podSpec.Containers}} {
+		for i := range containers.containers {
+			container := &containers.containers[i]
+			path := fmt.Sprintf("%s[%s]", containers.field, container.Name)
+			for j := range container.Command {
+				renderer.render(fmt.Sprintf("%s.command[%d]", path, j), &container.Command[j])
+			}
+			for j := range container.Args {
+				renderer.render(fmt.Sprintf("%s.args[%d]", path, j), &container.Args[j])
+			}
+			for j := range container.Env {
+				renderer.render(fmt.Sprintf("%s.env[%s].value", path, container.Env[j].Name), &container.Env[j].Value)
+			}
+			for j := range container.VolumeMounts {
+				renderer.render(fmt.Sprintf("%s.volumeMounts[%s].mountPath", path, container.VolumeMounts[j].Name), &container.VolumeMounts[j].MountPath)
+				renderer.render(fmt.Sprintf("%s.volumeMounts[%s].subPath", path, container.VolumeMounts[j].Name), &container.VolumeMounts[j].SubPath)
+			}
+		}
+	}
+	for i := range podSpec.Volumes {
+		if hostPath := podSpec.Volumes[i].HostPath; hostPath != nil {
+			renderer.render(fmt.Sprintf("volumes[%s].hostPath.path", podSpec.Volumes[i].Name), &hostPath.Path)
+		}
+	}
+	return renderer.err
+}
+
+// nodeTemplateRenderer renders values in place and keeps the first error.
+type nodeTemplateRenderer struct {
+	data nodeTemplateData
+	err  error
+}
+
+func (r *nodeTemplateRenderer) render(field string, value *string) {
+	if r.err != nil || !strings.Contains(*value, "{{") {
+		return
+	}
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(*value)
+	if err != nil {
+		r.err = &templateRenderError{field: field, err: err}
+		return
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, r.data); err != nil {
+		r.err = &templateRenderError{field: field, err: err}
+		return
+	}
+	*value = rendered.String()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// ReasonOverrideFailed is the failure reason of a node whose template override can't be applied.
+const ReasonOverrideFailed = "OverrideFailed"
+
+// overrideError is a failure to apply a template override.
+type overrideError struct {
+	name string
+	err  error
+}
+
+func (e *overrideError) Error() string {
+	return fmt.Sprintf("unable to apply override %s: %v", e.name, e.err)
+}
+
+func (e *overrideError) Unwrap() error {
+	return e.err
+}
+
+// validateOverrides checks the selectors of the template overrides of the CronSet.
+func validateOverrides(cronSet *batchv1alpha1.CronSet) error {
+	for _, override := range cronSet.Spec.Overrides {
+		if _, err := metav1.LabelSelectorAsSelector(&override.Selector); err != nil {
+			return fmt.Errorf("invalid selector of override %s: %w", override.Name, err)
+		}
+	}
+	return nil
+}
+
+// findOverride returns the first template override of the CronSet whose selector matches the node.
+func findOverride(cronSet *batchv1alpha1.CronSet, node *corev1.Node) *batchv1alpha1.CronJobTemplateOverride {
+	for i, override := range cronSet.Spec.Overrides {
+		selector, err := metav1.LabelSelectorAsSelector(&override.Selector)
+		if err == nil && selector.Matches(labels.Set(node.Labels)) {
+			return &cronSet.Spec.Overrides[i]
+		}
+	}
+	return nil
+}
+
+// applyOverride returns the CronJob template of the CronSet patched by the override.
+func applyOverride(cronSet *batchv1alpha1.CronSet, override *batchv1alpha1.CronJobTemplateOverride) (*batchv1alpha1.CronJobTemplateSpec, error) {
+	original, err := json.Marshal(cronSet.Spec.CronJobTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch override.PatchType {
+	case batchv1alpha1.OverridePatchJSON:
+		patch, err := jsonpatch.DecodePatch(override.Patch.Raw)
+		if err != nil {
+			return nil, &overrideError{name: override.Name, err: err}
+		}
+		if patched, err = patch.Apply(original); err != nil {
+			return nil, &overrideError{name: override.Name, err: err}
+		}
+	default:
+		// The template has the metadata and spec of a CronJob, so it is patched as one.
+		if patched, err = strategicpatch.StrategicMergePatch(original, override.Patch.Raw, batchv1.CronJob{}); err != nil {
+			return nil, &overrideError{name: override.Name, err: err}
+		}
+	}
+
+	template := &batchv1alpha1.CronJobTemplateSpec{}
+	if err := json.Unmarshal(patched, template); err != nil {
+		return nil, &overrideError{name: override.Name, err: err}
+	}
+	return template, nil
+}
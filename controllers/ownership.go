@@ -0,0 +1,162 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// OrphanedFromLabel marks the CronJobs orphaned by a deleted CronSet with the Orphan deletion
+	// policy. Its value is the name of the CronSet, truncated like the owner label.
+	OrphanedFromLabel = "grasse.io/orphaned-from"
+	// OrphanFinalizer lets a CronSet with the Orphan deletion policy orphan its CronJobs before it is deleted.
+	OrphanFinalizer = "cronset.grasse.io/orphan-cronjobs"
+)
+
+// listOwnedCronJobs returns the CronJobs in the namespace of the CronSet that carry its UID label
+// and are controlled by it.
+func (r *CronSetReconciler) listOwnedCronJobs(ctx context.Context, cronSet *batchv1alpha1.CronSet) ([]batchv1.CronJob, error) {
+	cronJobList := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronJobList,
+		client.InNamespace(cronSet.Namespace),
+		client.MatchingLabels{OwnerUIDLabel: string(cronSet.UID)},
+	); err != nil {
+		return nil, err
+	}
+
+	var cronJobs []batchv1.CronJob
+	for _, cronJob := range cronJobList.Items {
+		if metav1.IsControlledBy(&cronJob, cronSet) {
+			cronJobs = append(cronJobs, cronJob)
+		}
+	}
+	return cronJobs, nil
+}
+
+// migrateLegacyCronJobs adds the owner UID label to the CronJobs created by older versions of the
+// controller, which were only labeled with the name of their CronSet.
+func (r *CronSetReconciler) migrateLegacyCronJobs(ctx context.Context, cronSet *batchv1alpha1.CronSet) error {
+	withoutUID, _ := labels.NewRequirement(OwnerUIDLabel, selection.DoesNotExist, nil)
+	selector := labels.SelectorFromSet(labels.Set{OwnerLabel: truncateLabelValue(cronSet.Name)}).Add(*withoutUID)
+
+	cronJobList := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronJobList,
+		client.InNamespace(cronSet.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	); err != nil {
+		return err
+	}
+
+	for _, cronJob := range cronJobList.Items {
+		if !metav1.IsControlledBy(&cronJob, cronSet) {
+			continue
+		}
+		patch := client.MergeFrom(cronJob.DeepCopy())
+		cronJob.Labels[OwnerUIDLabel] = string(cronSet.UID)
+		if err := r.Patch(ctx, &cronJob, patch); err != nil {
+			return err
+		}
+		r.Log.Info("Migrate legacy CronJob", "cronset", cronSet.Name, "cronjob", cronJob.Name)
+	}
+	return nil
+}
+
+// syncDeletionPolicy adds the OrphanFinalizer to the CronSet with the Orphan deletion policy, and
+// removes it otherwise.
+func (r *CronSetReconciler) syncDeletionPolicy(ctx context.Context, cronSet *batchv1alpha1.CronSet) error {
+	var changed bool
+	if cronSet.Spec.DeletionPolicy == batchv1alpha1.DeletionPolicyOrphan {
+		changed = controllerutil.AddFinalizer(cronSet, OrphanFinalizer)
+	} else {
+		changed = controllerutil.RemoveFinalizer(cronSet, OrphanFinalizer)
+	}
+	if !changed {
+		return nil
+	}
+	return r.Update(ctx, cronSet)
+}
+
+// orphanCronJobs removes the owner reference and the owner labels of the CronJobs of a deleted
+// CronSet holding the OrphanFinalizer, labels them with OrphanedFromLabel, then removes the finalizer.
+func (r *CronSetReconciler) orphanCronJobs(ctx context.Context, cronSet *batchv1alpha1.CronSet, events *eventAggregator) error {
+	if !controllerutil.ContainsFinalizer(cronSet, OrphanFinalizer) {
+		return nil
+	}
+	cronJobs, err := r.listOwnedCronJobs(ctx, cronSet)
+	if err != nil {
+		return err
+	}
+	for _, cronJob := range cronJobs {
+		patch := client.MergeFromWithOptions(cronJob.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		cronJob.OwnerReferences = slices.DeleteFunc(cronJob.OwnerReferences, func(ownerRef metav1.OwnerReference) bool {
+			return ownerRef.UID == cronSet.UID
+		})
+		delete(cronJob.Labels, OwnerLabel)
+		delete(cronJob.Labels, OwnerUIDLabel)
+		cronJob.Labels[OrphanedFromLabel] = truncateLabelValue(cronSet.Name)
+		if err := r.Patch(ctx, &cronJob, patch); err != nil {
+			return err
+		}
+		events.add(corev1.EventTypeNormal, EventReasonOrphaned, "Orphaned CronJob %s", cronJob.Name)
+		r.Log.Info("Orphan CronJob", "cronset", cronSet.Name, "cronjob", cronJob.Name)
+	}
+
+	controllerutil.RemoveFinalizer(cronSet, OrphanFinalizer)
+	return r.Update(ctx, cronSet)
+}
+
+// adoptOrphanedCronJobs takes back the CronJobs orphaned by a deleted CronSet with the same name
+// in the same namespace. CronJobs already controlled by another object are left alone.
+func (r *CronSetReconciler) adoptOrphanedCronJobs(ctx context.Context, cronSet *batchv1alpha1.CronSet, events *eventAggregator) error {
+	cronJobList := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronJobList,
+		client.InNamespace(cronSet.Namespace),
+		client.MatchingLabels{OrphanedFromLabel: truncateLabelValue(cronSet.Name)},
+	); err != nil {
+		return err
+	}
+
+	for _, cronJob := range cronJobList.Items {
+		if metav1.GetControllerOf(&cronJob) != nil {
+			continue
+		}
+		patch := client.MergeFromWithOptions(cronJob.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if err := controllerutil.SetControllerReference(cronSet, &cronJob, r.Scheme); err != nil {
+			return err
+		}
+		delete(cronJob.Labels, OrphanedFromLabel)
+		cronJob.Labels[OwnerLabel] = truncateLabelValue(cronSet.Name)
+		cronJob.Labels[OwnerUIDLabel] = string(cronSet.UID)
+		if err := r.Patch(ctx, &cronJob, patch); err != nil {
+			return err
+		}
+		events.add(corev1.EventTypeNormal, EventReasonAdopted, "Adopted orphaned CronJob %s", cronJob.Name)
+		r.Log.Info("Adopt orphaned CronJob", "cronset", cronSet.Name, "cronjob", cronJob.Name)
+	}
+	return nil
+}
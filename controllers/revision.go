@@ -0,0 +1,67 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// templateSpec holds the parts of a CronSet spec that shape the generated CronJobs.
+type templateSpec struct {
+	CronJobTemplate    batchv1alpha1.CronJobTemplateSpec       `json:"cronJobTemplate"`
+	DefaultTolerations batchv1alpha1.DefaultTolerationsPolicy  `json:"defaultTolerations,omitempty"`
+	Overrides          []batchv1alpha1.CronJobTemplateOverride `json:"overrides,omitempty"`
+	Stagger            *batchv1alpha1.Stagger                  `json:"stagger,omitempty"`
+}
+
+func newTemplateSpec(cronSet *batchv1alpha1.CronSet) templateSpec {
+	return templateSpec{
+		CronJobTemplate:    cronSet.Spec.CronJobTemplate,
+		DefaultTolerations: cronSet.Spec.DefaultTolerations,
+		Overrides:          cronSet.Spec.Overrides,
+		Stagger:            cronSet.Spec.Stagger,
+	}
+}
+
+// withTemplate returns a copy of the CronSet with the given template.
+func withTemplate(cronSet *batchv1alpha1.CronSet, spec *templateSpec) *batchv1alpha1.CronSet {
+	templateCronSet := cronSet.DeepCopy()
+	templateCronSet.Spec.CronJobTemplate = spec.CronJobTemplate
+	templateCronSet.Spec.DefaultTolerations = spec.DefaultTolerations
+	templateCronSet.Spec.Overrides = spec.Overrides
+	templateCronSet.Spec.Stagger = spec.Stagger
+	return templateCronSet
+}
+
+// computeTemplateHash returns a stable hash of the CronJob template of the CronSet, which is
+// safe to be used as a label value. It is also the hash of the ControllerRevision of the template.
+// The collision count of the CronSet, if any, is mixed into the hash.
+func computeTemplateHash(cronSet *batchv1alpha1.CronSet) string {
+	data, _ := json.Marshal(newTemplateSpec(cronSet))
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(data)
+	if cronSet.Status.CollisionCount != nil {
+		_, _ = hasher.Write([]byte(strconv.Itoa(int(*cronSet.Status.CollisionCount))))
+	}
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+// rolloutPlan decides which CronJobs are updated to the current template in a reconcile.
+type rolloutPlan struct {
+	// heldNodes are the nodes whose CronJob is kept on its previous template.
+	heldNodes map[string]bool
+	// updatedCount is the number of CronJobs on the current template once the plan is applied.
+	updatedCount int32
+	// unavailableCount is the number of CronJobs on the current template whose next run
+	// hasn't succeeded yet once the plan is applied.
+	unavailableCount int32
+	// inProgress is true while a rolling update is waiting to update more CronJobs or for
+	// updated CronJobs to succeed.
+	inProgress bool
+}
+
+// planRollout applies the update strategy of the CronSet to the existing CronJobs, indexed by name,
+// whose jobs are grouped by CronJob UID.
+// CronJobs of new nodes are always created from the current template, and the CronJobs of suspended
+// nodes are updated without waiting, as they won't run anyway. While a canary is progressing, only
+// the CronJobs of the canary nodes are updated.
+func planRollout(cronSet *batchv1alpha1.CronSet, eligibleNodes []corev1.Node, cronJobNames map[string]string,
+	existingCronJobs map[string]*batchv1.CronJob, jobsByCronJob map[types.UID][]batchv1.Job, suspendedNodes map[string]bool,
+	canaryNodes map[string]bool) (*rolloutPlan, error) {
+	plan := &rolloutPlan{heldNodes: make(map[string]bool)}
+	templateHash := computeTemplateHash(cronSet)
+	neverRuns := ptr.Deref(cronSet.Spec.CronJobTemplate.Spec.Suspend, false)
+
+	nodeNames := make([]string, 0, len(eligibleNodes))
+	for _, node := range eligibleNodes {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	sort.Strings(nodeNames)
+
+	var outdatedNodes []string
+	partitioned := make(map[string]bool)
+	partition := rolloutPartition(cronSet)
+	for i, nodeName := range nodeNames {
+		cronJob, ok := existingCronJobs[cronJobNames[nodeName]]
+		switch {
+		case !ok:
+			plan.updatedCount++
+		case cronJob.Labels[TemplateHashLabel] == templateHash:
+			plan.updatedCount++
+			if !neverRuns && !suspendedNodes[nodeName] && awaitingSuccessfulRun(cronJob, jobsByCronJob[cronJob.UID]) {
+				plan.unavailableCount++
+			}
+		default:
+			outdatedNodes = append(outdatedNodes, nodeName)
+			partitioned[nodeName] = i < partition
+		}
+	}
+
+	strategy := cronSet.Spec.UpdateStrategy
+	switch {
+	case strategy == nil:
+		plan.updatedCount += int32(len(outdatedNodes))
+	case strategy.Type == batchv1alpha1.OnDeleteCronSetStrategyType:
+		for _, nodeName := range outdatedNodes {
+			plan.heldNodes[nodeName] = true
+		}
+	default:
+		maxUnavailable, err := maxUnavailable(cronSet, len(eligibleNodes))
+		if err != nil {
+			return nil, err
+		}
+		budget := maxUnavailable - int(plan.unavailableCount)
+		for _, nodeName := range outdatedNodes {
+			switch {
+			case canaryNodes != nil && canaryNodes[nodeName]:
+				plan.updatedCount++
+				if !neverRuns && !suspendedNodes[nodeName] {
+					plan.unavailableCount++
+				}
+			case canaryNodes != nil:
+				plan.heldNodes[nodeName] = true
+				plan.inProgress = true
+			case partitioned[nodeName]:
+				plan.heldNodes[nodeName] = true
+			case neverRuns || suspendedNodes[nodeName]:
+				plan.updatedCount++
+			case budget > 0:
+				budget--
+				plan.updatedCount++
+				plan.unavailableCount++
+			default:
+				plan.heldNodes[nodeName] = true
+				plan.inProgress = true
+			}
+		}
+		if plan.unavailableCount > 0 {
+			plan.inProgress = true
+		}
+	}
+	return plan, nil
+}
+
+// awaitingSuccessfulRun reports whether the CronJob was updated to a new template and none of its
+// jobs created since succeeded. Jobs created before the update ran the previous template, even if
+// they finish later. CronJobs created from their current template are not awaited.
+func awaitingSuccessfulRun(cronJob *batchv1.CronJob, jobs []batchv1.Job) bool {
+	updatedAt, err := time.Parse(time.RFC3339, cronJob.Annotations[TemplateUpdatedAnnotation])
+	if err != nil {
+		return false
+	}
+	for _, job := range jobs {
+		if !job.CreationTimestamp.Time.Before(updatedAt) && hasJobCondition(&job, batchv1.JobComplete) {
+			return false
+		}
+	}
+	return true
+}
+
+// maxUnavailable resolves the maxUnavailable of the rolling update against the number of eligible
+// nodes. It is at least 1, so that a rollout always makes progress.
+func maxUnavailable(cronSet *batchv1alpha1.CronSet, eligibleNodeCount int) (int, error) {
+	rollingUpdate := cronSet.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.MaxUnavailable == nil {
+		return 1, nil
+	}
+	value, err := intstr.GetScaledValueFromIntOrPercent(rollingUpdate.MaxUnavailable, eligibleNodeCount, true)
+	if err != nil {
+		return 0, fmt.Errorf("invalid updateStrategy.rollingUpdate.maxUnavailable: %w", err)
+	}
+	return max(value, 1), nil
+}
+
+func rolloutPartition(cronSet *batchv1alpha1.CronSet) int {
+	strategy := cronSet.Spec.UpdateStrategy
+	if strategy == nil || strategy.RollingUpdate == nil {
+		return 0
+	}
+	return int(ptr.Deref(strategy.RollingUpdate.Partition, 0))
+}
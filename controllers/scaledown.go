@@ -0,0 +1,107 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"slices"
+	"strings"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotations understood by the cluster autoscaler and Karpenter.
+const (
+	ScaleDownDisabledAnnotation = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+	SafeToEvictAnnotation       = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+	DoNotDisruptAnnotation      = "karpenter.sh/do-not-disrupt"
+)
+
+const (
+	// ScaleDownDisabledByAnnotation lists the CronSets, as "<namespace>/<name>", running a Job on the node.
+	// The autoscaler annotations of the node are set while the list isn't empty.
+	ScaleDownDisabledByAnnotation = "cronset.grasse.io/scale-down-disabled-by"
+	// ScaleDownAnnotationsAnnotation lists the autoscaler annotations added to the node by the controller,
+	// which are the only ones it removes. Annotations set on the node beforehand are left untouched.
+	ScaleDownAnnotationsAnnotation = "cronset.grasse.io/scale-down-annotations"
+)
+
+// scaleDownDisabledNodeAnnotations are added to the node with the Node scale down protection.
+var scaleDownDisabledNodeAnnotations = []string{ScaleDownDisabledAnnotation, DoNotDisruptAnnotation}
+
+// scaleDownDisabledPodAnnotations are added to the pod template with the Pod scale down protection.
+var scaleDownDisabledPodAnnotations = map[string]string{
+	SafeToEvictAnnotation:  "false",
+	DoNotDisruptAnnotation: "true",
+}
+
+func scaleDownProtectionPolicy(cronSet *batchv1alpha1.CronSet) batchv1alpha1.ScaleDownProtectionPolicy {
+	if cronSet.Spec.ScaleDownProtection == "" {
+		return batchv1alpha1.ScaleDownProtectionNone
+	}
+	return cronSet.Spec.ScaleDownProtection
+}
+
+// updateScaleDownProtection adds the CronSet to the ScaleDownDisabledByAnnotation of the node while
+// a Job of the CronSet is active on it with the Node scale down protection, and removes it otherwise.
+// The autoscaler annotations missing from the node are added along with the first CronSet, and removed
+// along with the last one. It reports whether the node changed.
+func updateScaleDownProtection(cronSet *batchv1alpha1.CronSet, node *corev1.Node, active bool) bool {
+	key := cronSet.Namespace + "/" + cronSet.Name
+	var cronSets []string
+	if value := node.Annotations[ScaleDownDisabledByAnnotation]; value != "" {
+		cronSets = strings.Split(value, ",")
+	}
+	protect := active && scaleDownProtectionPolicy(cronSet) == batchv1alpha1.ScaleDownProtectionNode
+	if slices.Contains(cronSets, key) == protect {
+		return false
+	}
+
+	if protect {
+		cronSets = append(cronSets, key)
+		slices.Sort(cronSets)
+	} else {
+		cronSets = slices.DeleteFunc(cronSets, func(cronSet string) bool { return cronSet == key })
+	}
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	if len(cronSets) == 0 {
+		for _, annotation := range strings.Split(node.Annotations[ScaleDownAnnotationsAnnotation], ",") {
+			if slices.Contains(scaleDownDisabledNodeAnnotations, annotation) {
+				delete(node.Annotations, annotation)
+			}
+		}
+		delete(node.Annotations, ScaleDownAnnotationsAnnotation)
+		delete(node.Annotations, ScaleDownDisabledByAnnotation)
+		return true
+	}
+	if _, ok := node.Annotations[ScaleDownDisabledByAnnotation]; !ok {
+		var added []string
+		for _, annotation := range scaleDownDisabledNodeAnnotations {
+			if _, ok := node.Annotations[annotation]; !ok {
+				node.Annotations[annotation] = "true"
+				added = append(added, annotation)
+			}
+		}
+		if len(added) > 0 {
+			node.Annotations[ScaleDownAnnotationsAnnotation] = strings.Join(added, ",")
+		}
+	}
+	node.Annotations[ScaleDownDisabledByAnnotation] = strings.Join(cronSets, ",")
+	return true
+}
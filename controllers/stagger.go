@@ -0,0 +1,192 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+)
+
+// maxStaggerMinutes bounds the stagger window to a day.
+const maxStaggerMinutes = 24 * 60
+
+// scheduleMacros are the predefined schedules which can be staggered.
+var scheduleMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// scheduleFieldRanges are the values an H token of each schedule field expands to. Like in Jenkins,
+// the day of the month stops at 28 so that it exists in every month.
+var scheduleFieldRanges = [5]struct{ min, max int }{{0, 59}, {0, 23}, {1, 28}, {1, 12}, {0, 6}}
+
+var (
+	hashTokenPattern  = regexp.MustCompile(`^H(?:\((\d+)-(\d+)\))?(?:/(\d+))?$`)
+	minuteStepPattern = regexp.MustCompile(`^(\*|\d+)(?:-59)?/(\d+)$`)
+)
+
+// renderSchedule returns the schedule of the CronJob of a node: the H tokens of the schedule of the
+// CronSet are replaced by values derived from a hash of the node identifier, and the schedule is
+// shifted by the stagger offset of the node. Both are stable across reconciles.
+func renderSchedule(cronSet *batchv1alpha1.CronSet, nodeIdentifier string) (string, error) {
+	schedule := cronSet.Spec.CronJobTemplate.Spec.Schedule
+	window := staggerWindowMinutes(cronSet)
+	hasHashTokens := containsHashToken(schedule)
+	if !hasHashTokens && window == 0 {
+		return schedule, nil
+	}
+
+	if expanded, ok := scheduleMacros[schedule]; ok {
+		schedule = expanded
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != len(scheduleFieldRanges) {
+		if hasHashTokens {
+			return "", fmt.Errorf("invalid schedule %q: H tokens require a schedule of 5 fields", schedule)
+		}
+		// Other schedules, e.g. @every, can't be staggered.
+		return cronSet.Spec.CronJobTemplate.Spec.Schedule, nil
+	}
+
+	for i, field := range fields {
+		expanded, err := expandHashTokens(field, i, nodeIdentifier)
+		if err != nil {
+			return "", fmt.Errorf("invalid schedule %q: %w", schedule, err)
+		}
+		fields[i] = expanded
+	}
+	if window > 0 {
+		staggerFields(fields, int(scheduleHash(nodeIdentifier, "stagger")%uint32(window)))
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// staggerWindowMinutes returns the stagger window of the CronSet in minutes, 0 if the CronJobs are
+// not staggered.
+func staggerWindowMinutes(cronSet *batchv1alpha1.CronSet) int {
+	if cronSet.Spec.Stagger == nil {
+		return 0
+	}
+	window := int(cronSet.Spec.Stagger.Window.Minutes())
+	if window <= 1 {
+		return 0
+	}
+	return min(window, maxStaggerMinutes)
+}
+
+// isHashToken reports whether the item of a schedule field is an H token. Names containing an H,
+// e.g. THU, are not.
+func isHashToken(item string) bool {
+	return item == "H" || strings.HasPrefix(item, "H(") || strings.HasPrefix(item, "H/")
+}
+
+// containsHashToken reports whether any item of the schedule is an H token.
+func containsHashToken(schedule string) bool {
+	for _, field := range strings.Fields(schedule) {
+		for _, item := range strings.Split(field, ",") {
+			if isHashToken(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandHashTokens replaces the H, H(a-b), H/n and H(a-b)/n items of a schedule field.
+func expandHashTokens(field string, fieldIndex int, nodeIdentifier string) (string, error) {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		if !isHashToken(item) {
+			continue
+		}
+		match := hashTokenPattern.FindStringSubmatch(item)
+		if match == nil {
+			return "", fmt.Errorf("invalid H token %q", item)
+		}
+		low, high := scheduleFieldRanges[fieldIndex].min, scheduleFieldRanges[fieldIndex].max
+		if match[1] != "" {
+			low, _ = strconv.Atoi(match[1])
+			high, _ = strconv.Atoi(match[2])
+			if low < scheduleFieldRanges[fieldIndex].min || high > scheduleFieldRanges[fieldIndex].max || low > high {
+				return "", fmt.Errorf("invalid range of H token %q", item)
+			}
+		}
+		hash := scheduleHash(nodeIdentifier, strconv.Itoa(fieldIndex))
+		if match[3] == "" {
+			items[i] = strconv.Itoa(low + int(hash%uint32(high-low+1)))
+			continue
+		}
+		step, _ := strconv.Atoi(match[3])
+		if step == 0 {
+			return "", fmt.Errorf("invalid step of H token %q", item)
+		}
+		start := low + int(hash%uint32(min(step, high-low+1)))
+		items[i] = fmt.Sprintf("%d-%d/%d", start, high, step)
+	}
+	return strings.Join(items, ","), nil
+}
+
+// staggerFields shifts the minute and hour fields of a schedule by the offset in minutes.
+// A fixed time of day is shifted as a whole, wrapping at midnight without changing the day fields.
+// When the day fields are restricted, wrapping would move the run to another day, so the offset is
+// taken modulo the rest of the day instead. Otherwise only the minute is shifted: a fixed minute by
+// the offset modulo an hour, and a minute step by the offset modulo the step. Other minute fields are
+// left unchanged.
+func staggerFields(fields []string, offset int) {
+	minute, minuteErr := strconv.Atoi(fields[0])
+	hour, hourErr := strconv.Atoi(fields[1])
+	switch {
+	case minuteErr == nil && hourErr == nil:
+		timeOfDay := hour*60 + minute
+		restOfDay := maxStaggerMinutes - timeOfDay
+		if (fields[2] != "*" || fields[3] != "*" || fields[4] != "*") && restOfDay > 0 {
+			offset %= restOfDay
+		}
+		timeOfDay = (timeOfDay + offset) % maxStaggerMinutes
+		fields[0], fields[1] = strconv.Itoa(timeOfDay%60), strconv.Itoa(timeOfDay/60)
+	case minuteErr == nil:
+		fields[0] = strconv.Itoa((minute + offset) % 60)
+	default:
+		match := minuteStepPattern.FindStringSubmatch(fields[0])
+		if match == nil {
+			return
+		}
+		start, _ := strconv.Atoi(match[1])
+		step, _ := strconv.Atoi(match[2])
+		if step == 0 {
+			return
+		}
+		fields[0] = fmt.Sprintf("%d/%d", (start+offset)%step, step)
+	}
+}
+
+// scheduleHash hashes the node identifier with a salt, so that every field gets its own value.
+func scheduleHash(nodeIdentifier string, salt string) uint32 {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(nodeIdentifier + "/" + salt))
+	return hasher.Sum32()
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reasons of the CronSet conditions.
+const (
+	ReasonAllCronJobsScheduled = "AllCronJobsScheduled"
+	ReasonCronJobsMissing      = "CronJobsMissing"
+	ReasonCronJobsChanged      = "CronJobsChanged"
+	ReasonCronJobsUpToDate     = "CronJobsUpToDate"
+	ReasonRollingUpdate        = "RollingUpdate"
+	ReasonCanaryInProgress     = "CanaryInProgress"
+	ReasonCanaryPromoted       = "CanaryPromoted"
+	ReasonCanaryFailed         = "CanaryFailed"
+	ReasonCanaryNodesMissing   = "CanaryNodesMissing"
+	ReasonApplyFailed          = "ApplyFailed"
+	ReasonApplySucceeded       = "ApplySucceeded"
+	ReasonInvalidSpec          = "InvalidSpec"
+	ReasonNodesEligible        = "NodesEligible"
+	ReasonNoNodesSelected      = "NoNodesSelected"
+	ReasonAllNodesExcluded     = "AllNodesExcluded"
+)
+
+// maxReportedFailures bounds the number of node failures listed in a condition message.
+const maxReportedFailures = 5
+
+// maxFailureMessageLength bounds the length of the error message of a node failure.
+const maxFailureMessageLength = 1024
+
+// maxConditionMessageLength bounds the length of the failure message of a condition, well below the
+// 32768 bytes allowed by the API.
+const maxConditionMessageLength = 4096
+
+// maxNodeStatuses bounds the number of entries in status.nodes.
+const maxNodeStatuses = 256
+
+// nodeFailure is a failure to apply the CronJob of a node.
+type nodeFailure struct {
+	reason  string
+	message string
+}
+
+func newNodeFailure(err error) nodeFailure {
+	reason := string(apierrors.ReasonForError(err))
+	if reason == string(metav1.StatusReasonUnknown) {
+		reason = ReasonApplyFailed
+	}
+	var alreadyOwned *controllerutil.AlreadyOwnedError
+	if errors.As(err, &alreadyOwned) {
+		reason = "AlreadyOwned"
+	}
+	var renderErr *templateRenderError
+	if errors.As(err, &renderErr) {
+		reason = ReasonTemplateRenderFailed
+	}
+	var overrideErr *overrideError
+	if errors.As(err, &overrideErr) {
+		reason = ReasonOverrideFailed
+	}
+	return nodeFailure{reason: reason, message: truncateMessage(err.Error(), maxFailureMessageLength)}
+}
+
+// truncateMessage shortens the message to at most maxLength bytes, ending it with an ellipsis when
+// it is cut. It doesn't split multi-byte characters.
+func truncateMessage(message string, maxLength int) string {
+	const ellipsis = "..."
+	if len(message) <= maxLength {
+		return message
+	}
+	cut := maxLength - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + ellipsis
+}
+
+// buildNodeStatuses describes the CronJob of every node from the owned CronJobs and the failures of
+// the reconcile. Failed nodes come first, then the others by node name, up to maxNodeStatuses entries.
+func buildNodeStatuses(cronJobs []batchv1.CronJob, cronJobNames map[string]string, nodeFailures map[string]nodeFailure) []batchv1alpha1.CronSetNodeStatus {
+	nodeStatuses := make(map[string]*batchv1alpha1.CronSetNodeStatus)
+	for _, cronJob := range cronJobs {
+		nodeName := cronJob.Spec.JobTemplate.Spec.Template.Spec.NodeName
+		nodeStatuses[nodeName] = &batchv1alpha1.CronSetNodeStatus{
+			NodeName:           nodeName,
+			CronJobName:        cronJob.Name,
+			TemplateHash:       cronJob.Labels[TemplateHashLabel],
+			LastScheduleTime:   cronJob.Status.LastScheduleTime,
+			LastSuccessfulTime: cronJob.Status.LastSuccessfulTime,
+			AdoptedFrom:        cronJob.Annotations[AdoptedFromAnnotation],
+		}
+		if deletionTime, pending := pendingDeletionTime(&cronJob); pending {
+			nodeStatuses[nodeName].PendingDeletionTime = &metav1.Time{Time: deletionTime}
+		}
+	}
+	for nodeName, failure := range nodeFailures {
+		nodeStatus, ok := nodeStatuses[nodeName]
+		if !ok {
+			nodeStatus = &batchv1alpha1.CronSetNodeStatus{NodeName: nodeName, CronJobName: cronJobNames[nodeName]}
+			nodeStatuses[nodeName] = nodeStatus
+		}
+		nodeStatus.LastErrorReason = failure.reason
+		nodeStatus.LastErrorMessage = failure.message
+	}
+
+	result := make([]batchv1alpha1.CronSetNodeStatus, 0, len(nodeStatuses))
+	for _, nodeStatus := range nodeStatuses {
+		result = append(result, *nodeStatus)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		iFailed, jFailed := result[i].LastErrorReason != "", result[j].LastErrorReason != ""
+		if iFailed != jFailed {
+			return iFailed
+		}
+		return result[i].NodeName < result[j].NodeName
+	})
+	if len(result) > maxNodeStatuses {
+		result = result[:maxNodeStatuses]
+	}
+	return result
+}
+
+// setStatusConditions sets the conditions of the CronSet from the outcome of a reconcile.
+func setStatusConditions(cronSet *batchv1alpha1.CronSet, status CronSetStatus) {
+	generation := cronSet.Generation
+	conditions := &cronSet.Status.Conditions
+
+	switch {
+	case status.DesiredScheduledJobCount == 0 && len(status.ExcludedNodes) == 0:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetNoEligibleNodes, metav1.ConditionTrue, ReasonNoNodesSelected,
+			"No node matches the node selection of the CronSet", generation))
+	case status.DesiredScheduledJobCount == 0:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetNoEligibleNodes, metav1.ConditionTrue, ReasonAllNodesExcluded,
+			fmt.Sprintf("All %d selected node(s) are excluded, see status.excludedNodes", len(status.ExcludedNodes)), generation))
+	default:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetNoEligibleNodes, metav1.ConditionFalse, ReasonNodesEligible,
+			fmt.Sprintf("%d node(s) are eligible", status.DesiredScheduledJobCount), generation))
+	}
+
+	switch {
+	case len(status.NodeFailures) > 0:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetDegraded, metav1.ConditionTrue, ReasonApplyFailed,
+			failureMessage(status.NodeFailures), generation))
+	case status.Canary != nil && status.Canary.Phase == batchv1alpha1.CanaryPhaseFailed:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetDegraded, metav1.ConditionTrue, ReasonCanaryFailed,
+			fmt.Sprintf("Rolled back to revision %s: %s", status.Canary.PreviousRevision, status.Canary.Message), generation))
+	case status.CanaryNodesMissing:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetDegraded, metav1.ConditionTrue, ReasonCanaryNodesMissing,
+			"No eligible node matches the canary, the other nodes are held on the previous revision", generation))
+	default:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetDegraded, metav1.ConditionFalse, ReasonApplySucceeded,
+			"CronJobs are applied on every eligible node", generation))
+	}
+
+	switch {
+	case status.ChangedCronJobCount > 0 || len(status.NodeFailures) > 0:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetProgressing, metav1.ConditionTrue, ReasonCronJobsChanged,
+			fmt.Sprintf("%d CronJob(s) changed and %d node(s) failed in the last reconcile", status.ChangedCronJobCount, len(status.NodeFailures)), generation))
+	case status.RolloutInProgress:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetProgressing, metav1.ConditionTrue, ReasonRollingUpdate,
+			fmt.Sprintf("%d/%d CronJob(s) updated, %d awaiting a successful run", status.UpdatedCronJobCount, status.DesiredScheduledJobCount, status.UnavailableCronJobCount), generation))
+	default:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetProgressing, metav1.ConditionFalse, ReasonCronJobsUpToDate,
+			"CronJobs are up to date", generation))
+	}
+
+	switch {
+	case status.Canary == nil:
+		meta.RemoveStatusCondition(conditions, batchv1alpha1.CronSetCanaryPromoted)
+	case status.Canary.Phase == batchv1alpha1.CanaryPhasePromoted:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetCanaryPromoted, metav1.ConditionTrue, ReasonCanaryPromoted,
+			status.Canary.Message, generation))
+	case status.Canary.Phase == batchv1alpha1.CanaryPhaseFailed:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetCanaryPromoted, metav1.ConditionFalse, ReasonCanaryFailed,
+			status.Canary.Message, generation))
+	default:
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetCanaryPromoted, metav1.ConditionFalse, ReasonCanaryInProgress,
+			status.Canary.Message, generation))
+	}
+
+	// The CronJobs of departed nodes pending deletion don't count.
+	if status.MisScheduledJobCount == 0 && status.ScheduledCronJobCount == status.DesiredScheduledJobCount {
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetAvailable, metav1.ConditionTrue, ReasonAllCronJobsScheduled,
+			fmt.Sprintf("%d/%d CronJob(s) are scheduled", status.ScheduledCronJobCount, status.DesiredScheduledJobCount), generation))
+	} else {
+		meta.SetStatusCondition(conditions, newCondition(batchv1alpha1.CronSetAvailable, metav1.ConditionFalse, ReasonCronJobsMissing,
+			fmt.Sprintf("%d/%d CronJob(s) are scheduled", status.ScheduledCronJobCount, status.DesiredScheduledJobCount), generation))
+	}
+}
+
+// updateInvalidSpecStatus marks the CronSet as degraded because of an invalid spec.
+// It returns a terminal error, as the CronSet can't be reconciled until its spec changes.
+func (r *CronSetReconciler) updateInvalidSpecStatus(ctx context.Context, cronSet *batchv1alpha1.CronSet, specErr error) error {
+	generation := cronSet.Generation
+	cronSet.Status.ObservedGeneration = generation
+	meta.SetStatusCondition(&cronSet.Status.Conditions, newCondition(batchv1alpha1.CronSetDegraded, metav1.ConditionTrue, ReasonInvalidSpec,
+		specErr.Error(), generation))
+	meta.SetStatusCondition(&cronSet.Status.Conditions, newCondition(batchv1alpha1.CronSetProgressing, metav1.ConditionFalse, ReasonInvalidSpec,
+		"The CronSet can't be reconciled until its spec is fixed", generation))
+	r.Recorder.Event(cronSet, corev1.EventTypeWarning, ReasonInvalidSpec, specErr.Error())
+	if err := r.Status().Update(ctx, cronSet); err != nil {
+		return err
+	}
+	return reconcile.TerminalError(specErr)
+}
+
+func newCondition(conditionType string, status metav1.ConditionStatus, reason, message string, generation int64) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	}
+}
+
+// failureMessage summarizes the node failures, listing at most maxReportedFailures of them within
+// maxConditionMessageLength bytes.
+func failureMessage(nodeFailures map[string]nodeFailure) string {
+	nodeNames := make([]string, 0, len(nodeFailures))
+	for nodeName := range nodeFailures {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	message := fmt.Sprintf("Failed to apply CronJobs on %d node(s): ", len(nodeNames))
+	// Room is kept for the count of the failures left out.
+	budget := maxConditionMessageLength - len(message) - len(fmt.Sprintf("; and %d more", len(nodeNames)))
+	var details []string
+	length := 0
+	for i, nodeName := range nodeNames {
+		detail := fmt.Sprintf("%s: %s", nodeName, nodeFailures[nodeName].message)
+		if i == maxReportedFailures || length+len("; ")+len(detail) > budget {
+			details = append(details, fmt.Sprintf("and %d more", len(nodeNames)-i))
+			break
+		}
+		details = append(details, detail)
+		length += len("; ") + len(detail)
+	}
+	return message + strings.Join(details, "; ")
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// DrainingSinceAnnotation records when a protected node was first seen cordoned. The maximum
+	// termination delay of a cordoned node counts from it.
+	DrainingSinceAnnotation = "cronset.grasse.io/draining-since"
+	// NodeProtectionFinalizer keeps a CronSet until it released the nodes it protects.
+	NodeProtectionFinalizer = "cronset.grasse.io/node-protection"
+)
+
+// defaultTerminationMaxDelay is used when the termination protection doesn't set a maximum delay.
+const defaultTerminationMaxDelay = time.Hour
+
+// nodeFinalizer returns the finalizer the CronSet sets on the nodes it protects. Every CronSet has
+// its own finalizer, so that a node is deleted only once no CronSet protects it anymore.
+func nodeFinalizer(cronSet *batchv1alpha1.CronSet) string {
+	return "cronset.grasse.io/" + truncateLabelValue(cronSet.Namespace+"."+cronSet.Name)
+}
+
+func terminationMaxDelay(cronSet *batchv1alpha1.CronSet) time.Duration {
+	if cronSet.Spec.TerminationProtection == nil || cronSet.Spec.TerminationProtection.MaxDelay == nil {
+		return defaultTerminationMaxDelay
+	}
+	return cronSet.Spec.TerminationProtection.MaxDelay.Duration
+}
+
+// protectedPodLabels are added to the pod template of the CronJobs when the termination protection
+// is enabled. They select the pods of the node in its PodDisruptionBudget.
+func protectedPodLabels(cronSet *batchv1alpha1.CronSet, nodeName string) map[string]string {
+	return map[string]string{
+		OwnerUIDLabel: string(cronSet.UID),
+		NodeLabel:     truncateLabelValue(nodeName),
+	}
+}
+
+// terminationStartTime returns when the termination of the node began: its deletion, or else the
+// time it was first seen cordoned. The time is zero for a cordoned node which wasn't seen before.
+func terminationStartTime(node *corev1.Node) (time.Time, bool) {
+	if node.DeletionTimestamp != nil {
+		return node.DeletionTimestamp.Time, true
+	}
+	if !node.Spec.Unschedulable {
+		return time.Time{}, false
+	}
+	drainingSince, _ := time.Parse(time.RFC3339, node.Annotations[DrainingSinceAnnotation])
+	return drainingSince, true
+}
+
+// nodeProtectionEnabled reports whether the CronSet protects the nodes running its Jobs from their
+// termination or from the scale down by the cluster autoscalers.
+func nodeProtectionEnabled(cronSet *batchv1alpha1.CronSet) bool {
+	return cronSet.Spec.TerminationProtection != nil || scaleDownProtectionPolicy(cronSet) == batchv1alpha1.ScaleDownProtectionNode
+}
+
+// listActiveCronJobs returns the CronJobs with an unfinished Job, keyed by node name.
+func (r *CronSetReconciler) listActiveCronJobs(ctx context.Context, cronSet *batchv1alpha1.CronSet, cronJobs []batchv1.CronJob) (map[string]*batchv1.CronJob, error) {
+	jobsByCronJob, err := r.listJobsByCronJob(ctx, cronJobs)
+	if err != nil {
+		return nil, err
+	}
+	activeCronJobs := make(map[string]*batchv1.CronJob)
+	for i := range cronJobs {
+		for _, job := range jobsByCronJob[cronJobs[i].UID] {
+			if !hasJobCondition(&job, batchv1.JobComplete) && !hasJobCondition(&job, batchv1.JobFailed) {
+				activeCronJobs[cronJobs[i].Spec.JobTemplate.Spec.Template.Spec.NodeName] = &cronJobs[i]
+				break
+			}
+		}
+	}
+	return activeCronJobs, nil
+}
+
+// syncNodeProtection protects the nodes running an unfinished Job of the CronSet.
+// With the termination protection, the pods of the node are covered by a PodDisruptionBudget which
+// doesn't allow any eviction, and the node gets the finalizer of the CronSet. Both are removed once
+// the Job finishes, or once the maximum delay passed since the node was cordoned or deleted.
+// With the Node scale down protection, the node gets the annotations of the cluster autoscalers.
+// It returns when the next termination delay expires.
+func (r *CronSetReconciler) syncNodeProtection(ctx context.Context, cronSet *batchv1alpha1.CronSet,
+	cronJobs []batchv1.CronJob, events *eventAggregator, now time.Time) (time.Duration, error) {
+	if !nodeProtectionEnabled(cronSet) || cronSet.DeletionTimestamp != nil {
+		return 0, r.releaseNodes(ctx, cronSet)
+	}
+	if controllerutil.AddFinalizer(cronSet, NodeProtectionFinalizer) {
+		if err := r.Update(ctx, cronSet); err != nil {
+			return 0, err
+		}
+	}
+
+	activeCronJobs, err := r.listActiveCronJobs(ctx, cronSet, cronJobs)
+	if err != nil {
+		return 0, err
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return 0, err
+	}
+	finalizer := nodeFinalizer(cronSet)
+	maxDelay := terminationMaxDelay(cronSet)
+	protectedCronJobs := make(map[string]bool)
+	var requeueAfter time.Duration
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		patch := client.MergeFromWithOptions(node.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		changed := false
+
+		cronJob, active := activeCronJobs[node.Name]
+		protected := active && cronSet.Spec.TerminationProtection != nil
+		startTime, terminating := terminationStartTime(node)
+		if _, ok := node.Annotations[DrainingSinceAnnotation]; ok && !node.Spec.Unschedulable {
+			delete(node.Annotations, DrainingSinceAnnotation)
+			changed = true
+		}
+		if protected && terminating {
+			if startTime.IsZero() {
+				startTime = now
+				if node.Annotations == nil {
+					node.Annotations = make(map[string]string)
+				}
+				node.Annotations[DrainingSinceAnnotation] = now.UTC().Format(time.RFC3339)
+				changed = true
+			}
+			if remaining := startTime.Add(maxDelay).Sub(now); remaining > 0 {
+				requeueAfter = earliestRequeue(requeueAfter, remaining)
+			} else {
+				protected = false
+				if controllerutil.ContainsFinalizer(node, finalizer) {
+					events.add(corev1.EventTypeWarning, EventReasonTerminationDelayExceeded,
+						"Released node %s after %s although a Job of CronJob %s is still running", node.Name, maxDelay, cronJob.Name)
+				}
+			}
+		}
+
+		if protected {
+			protectedCronJobs[cronJob.Name] = true
+			if err := r.applyPodDisruptionBudget(ctx, cronSet, cronJob.Name, node.Name); err != nil {
+				return 0, err
+			}
+			// Finalizers can't be added to a node which is already being deleted.
+			if node.DeletionTimestamp == nil && controllerutil.AddFinalizer(node, finalizer) {
+				changed = true
+			}
+		} else if controllerutil.RemoveFinalizer(node, finalizer) {
+			changed = true
+		}
+		if updateScaleDownProtection(cronSet, node, active) {
+			changed = true
+		}
+
+		if changed {
+			if err := r.Patch(ctx, node, patch); err != nil {
+				return 0, err
+			}
+			r.Log.Info("Update protection of node", "cronset", cronSet.Name, "node", node.Name, "active", active, "protected", protected)
+		}
+	}
+
+	return requeueAfter, r.deletePodDisruptionBudgets(ctx, cronSet, protectedCronJobs)
+}
+
+// releaseNodes removes the finalizer and the scale down protection of the CronSet from every node,
+// deletes its PodDisruptionBudgets, then removes the finalizer of the CronSet itself.
+func (r *CronSetReconciler) releaseNodes(ctx context.Context, cronSet *batchv1alpha1.CronSet) error {
+	if err := r.deletePodDisruptionBudgets(ctx, cronSet, nil); err != nil {
+		return err
+	}
+	if !controllerutil.ContainsFinalizer(cronSet, NodeProtectionFinalizer) {
+		return nil
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return err
+	}
+	finalizer := nodeFinalizer(cronSet)
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		patch := client.MergeFromWithOptions(node.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		removedFinalizer := controllerutil.RemoveFinalizer(node, finalizer)
+		if !updateScaleDownProtection(cronSet, node, false) && !removedFinalizer {
+			continue
+		}
+		if err := r.Patch(ctx, node, patch); err != nil {
+			return err
+		}
+		r.Log.Info("Release node", "cronset", cronSet.Name, "node", node.Name)
+	}
+
+	controllerutil.RemoveFinalizer(cronSet, NodeProtectionFinalizer)
+	return r.Update(ctx, cronSet)
+}
+
+// applyPodDisruptionBudget creates the PodDisruptionBudget protecting the pods of the node, which is
+// named after its CronJob.
+func (r *CronSetReconciler) applyPodDisruptionBudget(ctx context.Context, cronSet *batchv1alpha1.CronSet, cronJobName string, nodeName string) error {
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cronJobName,
+			Namespace: cronSet.Namespace,
+		},
+	}
+	_, err := ctrl.CreateOrUpdate(ctx, r.Client, pdb, func() error {
+		pdb.Labels = map[string]string{
+			OwnerLabel:    truncateLabelValue(cronSet.Name),
+			OwnerUIDLabel: string(cronSet.UID),
+			NodeLabel:     truncateLabelValue(nodeName),
+		}
+		maxUnavailable := intstr.FromInt32(0)
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+		pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: protectedPodLabels(cronSet, nodeName)}
+		return controllerutil.SetControllerReference(cronSet, pdb, r.Scheme)
+	})
+	return err
+}
+
+// deletePodDisruptionBudgets deletes the PodDisruptionBudgets of the CronSet except the ones of the
+// given CronJobs.
+func (r *CronSetReconciler) deletePodDisruptionBudgets(ctx context.Context, cronSet *batchv1alpha1.CronSet, keep map[string]bool) error {
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	if err := r.List(ctx, pdbList,
+		client.InNamespace(cronSet.Namespace),
+		client.MatchingLabels{OwnerUIDLabel: string(cronSet.UID)},
+	); err != nil {
+		return err
+	}
+	for _, pdb := range pdbList.Items {
+		if keep[pdb.Name] || !metav1.IsControlledBy(&pdb, cronSet) {
+			continue
+		}
+		if err := r.Delete(ctx, &pdb, client.Preconditions{UID: &pdb.UID}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.Log.Info("Delete PodDisruptionBudget", "cronset", cronSet.Name, "pdb", pdb.Name)
+	}
+	return nil
+}
+
+// earliestRequeue returns the shorter of two requeue delays, where zero means no requeue.
+func earliestRequeue(a, b time.Duration) time.Duration {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	batchv1alpha1 "github.com/grasse-oss/cron-set-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// daemonSetTolerations are the tolerations the DaemonSet controller adds to its pods.
+var daemonSetTolerations = []corev1.Toleration{
+	{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeDiskPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeMemoryPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodePIDPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+}
+
+// hostNetworkTolerations are added on top of daemonSetTolerations for pods using the host network.
+var hostNetworkTolerations = []corev1.Toleration{
+	{Key: corev1.TaintNodeNetworkUnavailable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+}
+
+// podTolerations returns the tolerations of the pods created from the CronSet: the tolerations of
+// the pod template followed by the default tolerations of spec.defaultTolerations.
+// A default toleration is skipped when the template already has a toleration with the same key and effect.
+// The returned slice never shares its backing array with the CronSet.
+func podTolerations(cronSet *batchv1alpha1.CronSet) []corev1.Toleration {
+	podSpec := cronSet.Spec.CronJobTemplate.Spec.JobTemplate.Spec.Template.Spec
+	var tolerations []corev1.Toleration
+	for _, toleration := range podSpec.Tolerations {
+		tolerations = append(tolerations, *toleration.DeepCopy())
+	}
+
+	if cronSet.Spec.DefaultTolerations != batchv1alpha1.DefaultTolerationsDaemonSet {
+		return tolerations
+	}
+
+	defaults := daemonSetTolerations
+	if podSpec.HostNetwork {
+		defaults = append(append([]corev1.Toleration{}, daemonSetTolerations...), hostNetworkTolerations...)
+	}
+	for _, defaultToleration := range defaults {
+		if !hasToleration(tolerations, defaultToleration.Key, defaultToleration.Effect) {
+			tolerations = append(tolerations, defaultToleration)
+		}
+	}
+	return tolerations
+}
+
+func hasToleration(tolerations []corev1.Toleration, key string, effect corev1.TaintEffect) bool {
+	for _, toleration := range tolerations {
+		if toleration.Key == key && toleration.Effect == effect {
+			return true
+		}
+	}
+	return false
+}